@@ -0,0 +1,285 @@
+package walgo
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	// UnsupportedDigestErr is returned by the DigestAuth when the server
+	// asks for an algorithm or quality of protection it doesn't support.
+	UnsupportedDigestErr = errors.New("Unsupported digest challenge.")
+)
+
+// Authenticator adds credentials to outgoing requests. It is given to a
+// Requester using the WithAuthenticator option.
+type Authenticator interface {
+	// Authenticate adds the credentials to the request before it is sent.
+	Authenticate(req *http.Request) (err error)
+
+	// Challenge is called when the server responds with 401 (Unauthorized).
+	// If it returns true the request is authenticated and sent once more -
+	// eg. after refreshing the credentials. Streamed requests are never
+	// sent again, but Challenge is still called so later requests can use
+	// what it learns.
+	Challenge(req *http.Request, res Response) (retry bool, err error)
+}
+
+// WithAuthenticator makes the request authenticate using the given
+// Authenticator. It is applied after the header options so it can override
+// the Authorization header.
+func WithAuthenticator(a Authenticator) RequestOption {
+	return func(o *requestOptions) {
+		o.authenticator = a
+	}
+}
+
+// AuthenticatorFunc is an Authenticator adding credentials using a function.
+// It never retries on a challenge.
+type AuthenticatorFunc func(req *http.Request) (err error)
+
+// Authenticate calls the function.
+func (a AuthenticatorFunc) Authenticate(req *http.Request) (err error) {
+	return a(req)
+}
+
+// Challenge never retries.
+func (a AuthenticatorFunc) Challenge(req *http.Request, res Response) (retry bool, err error) {
+	return false, nil
+}
+
+// BasicAuth authenticates using HTTP Basic authentication.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate sets the Authorization header.
+func (a BasicAuth) Authenticate(req *http.Request) (err error) {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// Challenge never retries.
+func (a BasicAuth) Challenge(req *http.Request, res Response) (retry bool, err error) {
+	return false, nil
+}
+
+// HeaderAPIKey authenticates using an API key sent in a header.
+type HeaderAPIKey struct {
+	// Name of the header holding the key, eg. "X-Api-Key".
+	Name string
+	Key  string
+}
+
+// Authenticate sets the header.
+func (a HeaderAPIKey) Authenticate(req *http.Request) (err error) {
+	req.Header.Set(a.Name, a.Key)
+	return nil
+}
+
+// Challenge never retries.
+func (a HeaderAPIKey) Challenge(req *http.Request, res Response) (retry bool, err error) {
+	return false, nil
+}
+
+// QueryAPIKey authenticates using an API key sent as a query parameter.
+type QueryAPIKey struct {
+	// Name of the query parameter holding the key, eg. "api_key".
+	Name string
+	Key  string
+}
+
+// Authenticate sets the query parameter.
+func (a QueryAPIKey) Authenticate(req *http.Request) (err error) {
+	query := req.URL.Query()
+	query.Set(a.Name, a.Key)
+	req.URL.RawQuery = query.Encode()
+	return nil
+}
+
+// Challenge never retries.
+func (a QueryAPIKey) Challenge(req *http.Request, res Response) (retry bool, err error) {
+	return false, nil
+}
+
+// DigestAuth authenticates using HTTP Digest authentication (RFC 7616)
+// with the MD5 or SHA-256 algorithm. The first request to a server is
+// answered with a challenge which is used for the following requests.
+// Create it with NewDigestAuth.
+type DigestAuth struct {
+	username string
+	password string
+
+	lock      sync.Mutex
+	challenge map[string]string
+	count     int
+}
+
+// NewDigestAuth creates a new DigestAuth with the given credentials.
+func NewDigestAuth(username, password string) (a *DigestAuth) {
+	return &DigestAuth{username: username, password: password}
+}
+
+// Authenticate sets the Authorization header if a challenge has been
+// received.
+func (a *DigestAuth) Authenticate(req *http.Request) (err error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.challenge == nil {
+		return nil
+	}
+
+	cnonce := make([]byte, 16)
+	if _, err = rand.Read(cnonce); err != nil {
+		return err
+	}
+
+	a.count++
+	header, err := digestAuthorization(a.challenge, a.username, a.password, req.Method, req.URL.RequestURI(), a.count, hex.EncodeToString(cnonce))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(authorizationHeader, header)
+	return nil
+}
+
+// Challenge stores the Digest challenge from the response and retries.
+func (a *DigestAuth) Challenge(req *http.Request, res Response) (retry bool, err error) {
+	for _, value := range res.Header().Values("WWW-Authenticate") {
+		if len(value) < 7 || !strings.EqualFold(value[:7], "Digest ") {
+			continue
+		}
+
+		challenge := parseAuthParams(value[7:])
+
+		a.lock.Lock()
+		stale := strings.EqualFold(challenge["stale"], "true")
+		retry = a.challenge == nil || stale || a.challenge["nonce"] != challenge["nonce"]
+		a.challenge = challenge
+		a.count = 0
+		a.lock.Unlock()
+
+		return retry, nil
+	}
+
+	return false, nil
+}
+
+// Computes the Authorization header value for the challenge.
+func digestAuthorization(challenge map[string]string, username, password, method, uri string, count int, cnonce string) (header string, err error) {
+	var h func() hash.Hash
+	algorithm := challenge["algorithm"]
+	switch strings.ToUpper(algorithm) {
+	case "", "MD5":
+		h = md5.New
+	case "SHA-256":
+		h = sha256.New
+	default:
+		return "", UnsupportedDigestErr
+	}
+
+	hexHash := func(s string) string {
+		hh := h()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	qop := ""
+	if challenge["qop"] != "" {
+		for _, q := range strings.Split(challenge["qop"], ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", UnsupportedDigestErr
+		}
+	}
+
+	realm, nonce := challenge["realm"], challenge["nonce"]
+	ha1 := hexHash(username + ":" + realm + ":" + password)
+	ha2 := hexHash(method + ":" + uri)
+
+	parts := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, realm),
+		fmt.Sprintf(`nonce="%s"`, nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+	}
+
+	if algorithm != "" {
+		parts = append(parts, "algorithm="+algorithm)
+	}
+
+	if qop == "" {
+		parts = append(parts, fmt.Sprintf(`response="%s"`, hexHash(ha1+":"+nonce+":"+ha2)))
+	} else {
+		nc := fmt.Sprintf("%08x", count)
+		response := hexHash(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+		parts = append(parts,
+			fmt.Sprintf(`response="%s"`, response),
+			"qop="+qop,
+			"nc="+nc,
+			fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+
+	if opaque, ok := challenge["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf(`opaque="%s"`, opaque))
+	}
+
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+// Parses a comma separated list of key=value pairs where the values may be
+// quoted strings (as used in the WWW-Authenticate header).
+func parseAuthParams(s string) (params map[string]string) {
+	params = make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " ,\t")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return params
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+
+		params[key] = value
+	}
+}
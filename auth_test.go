@@ -0,0 +1,172 @@
+package walgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	res, err := Get(server.URL, nil, WithAuthenticator(BasicAuth{"user", "pass"}))
+	if err != nil || res.Code() != http.StatusOK {
+		t.Fatal("Basic auth failed:", err, res.Code())
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	var headers http.Header
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		query = r.URL.RawQuery
+	}))
+	defer server.Close()
+
+	r := NewRequester(http.DefaultClient, "", "", WithAuthenticator(HeaderAPIKey{"X-Api-Key", "secret"}))
+	if _, err := r.Get(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("X-Api-Key") != "secret" {
+		t.Fatal("API key header not sent.")
+	}
+
+	if _, err := r.Get(server.URL, ParameterMap{"a": "b"}, WithAuthenticator(QueryAPIKey{"api_key", "secret"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if query != "a=b&api_key=secret" || headers.Get("X-Api-Key") != "" {
+		t.Fatal("API key query not sent or default not overridden:", query)
+	}
+}
+
+func TestChallengeRefresh(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	a := &refreshingAuth{token: "stale"}
+	res, err := PostRaw(server.URL, nil, []byte("data"), WithAuthenticator(a))
+	if err != nil || res.Code() != http.StatusOK || hits != 2 {
+		t.Fatal("Request should be retried after refresh:", err, res.Code(), hits)
+	}
+
+	a.token = "expired"
+	a.next = "expired"
+	res, err = Get(server.URL, nil, WithAuthenticator(a))
+	if err != nil || res.Code() != http.StatusUnauthorized || hits != 4 {
+		t.Fatal("Request should only be retried once:", err, res.Code(), hits)
+	}
+}
+
+type refreshingAuth struct {
+	token string
+	next  string
+}
+
+func (a *refreshingAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+func (a *refreshingAuth) Challenge(req *http.Request, res Response) (bool, error) {
+	if a.next != "" {
+		a.token = a.next
+	} else {
+		a.token = "fresh"
+	}
+	return true, nil
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	// Example from RFC 2617 section 3.5.
+	challenge := parseAuthParams(`realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`)
+	header, err := digestAuthorization(challenge, "Mufasa", "Circle Of Life", http.MethodGet, "/dir/index.html", 1, "0a4f113b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(header, `response="6629fae49393a05397450978507c4ef1"`) {
+		t.Fatal("Unexpected digest:", header)
+	}
+
+	if !strings.Contains(header, "nc=00000001") || !strings.Contains(header, `opaque="5ccc069c403ebaf9f0171e9517f40e41"`) {
+		t.Fatal("Missing digest parameters:", header)
+	}
+
+	challenge["algorithm"] = "SHA-512-256"
+	if _, err = digestAuthorization(challenge, "Mufasa", "Circle Of Life", http.MethodGet, "/", 1, "0a4f113b"); err != UnsupportedDigestErr {
+		t.Fatal("Unsupported algorithm should fail:", err)
+	}
+}
+
+func TestDigestAuthStreamed(t *testing.T) {
+	challenge := map[string]string{"realm": "test", "qop": "auth", "nonce": "abc"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth", nonce="abc"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		params := parseAuthParams(auth[7:])
+		nc, _ := strconv.ParseInt(params["nc"], 16, 64)
+		expected, _ := digestAuthorization(challenge, "user", "pass", r.Method, r.URL.RequestURI(), int(nc), params["cnonce"])
+		if auth != expected {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	r := NewRequester(http.DefaultClient, "", "", WithAuthenticator(NewDigestAuth("user", "pass")))
+	res, err := r.PostReader(server.URL, nil, strings.NewReader("data"), -1)
+	if err != nil || res.Code() != http.StatusUnauthorized {
+		t.Fatal("Streamed body should not be sent again:", err, res.Code())
+	}
+
+	res, err = r.PostReader(server.URL, nil, strings.NewReader("data"), -1)
+	if err != nil || res.Code() != http.StatusOK {
+		t.Fatal("Challenge should be used by the next request:", err, res.Code())
+	}
+}
+
+func TestDigestAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth", algorithm=SHA-256, nonce="abc", opaque="xyz"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		params := parseAuthParams(auth[7:])
+		expected, _ := digestAuthorization(map[string]string{"realm": "test", "qop": "auth", "algorithm": "SHA-256", "nonce": "abc", "opaque": "xyz"},
+			"user", "pass", r.Method, r.URL.RequestURI(), 1, params["cnonce"])
+		if auth != expected {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	res, err := Get(server.URL+"/path", ParameterMap{"q": "1"}, WithAuthenticator(NewDigestAuth("user", "pass")))
+	if err != nil || res.Code() != http.StatusOK {
+		t.Fatal("Digest auth failed:", err, res.Code())
+	}
+}
@@ -0,0 +1,383 @@
+package walgo
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheStatus tells how a response was served by a CachingRequester.
+type CacheStatus int
+
+const (
+	// CacheNone is the status of responses not passed through a cache.
+	CacheNone CacheStatus = iota
+
+	// CacheMiss is the status of responses fetched from the server.
+	CacheMiss
+
+	// CacheHit is the status of responses served from the cache without
+	// contacting the server.
+	CacheHit
+
+	// CacheRevalidated is the status of responses served from the cache
+	// after the server confirmed they are still valid (304 Not Modified).
+	CacheRevalidated
+)
+
+// CacheEntry is a response stored in a CacheStore.
+type CacheEntry struct {
+	Code   int
+	Header http.Header
+	Data   []byte
+	Proto  string
+	URL    string
+
+	// Vary holds the values of the request headers named in the Vary
+	// header of the response.
+	Vary map[string]string
+
+	// Expires is the time the entry stops being fresh. Entries that are
+	// not fresh are revalidated before they are used.
+	Expires time.Time
+
+	// Received is the time the response was received or last revalidated.
+	Received time.Time
+}
+
+// CacheStore stores cached responses. Implementations must be safe to use
+// concurrently.
+type CacheStore interface {
+	// Get returns the entry stored with the key.
+	Get(key string) (e *CacheEntry, ok bool)
+
+	// Set stores the entry with the key.
+	Set(key string, e *CacheEntry)
+
+	// Delete removes the entry stored with the key.
+	Delete(key string)
+}
+
+// CachingRequester is a Requester caching GET responses following the
+// HTTP caching rules (RFC 9111). Fresh responses are served from the store
+// and stale responses are revalidated using ETag and Last-Modified. Other
+// methods are forwarded and invalidate the cached response for the URL.
+// Streamed requests bypass the cache.
+//
+// The cache is shared by everyone using the requester, so responses to
+// requests with credentials (an Authorization header, an Authenticator or
+// a Signer) are only stored if they are marked public. The max-age,
+// min-fresh and max-stale directives of the request are respected.
+type CachingRequester struct {
+	requestMethods
+
+	requester Requester
+	store     CacheStore
+}
+
+// NewCachingRequester creates a new Requester caching the responses from
+// the given Requester in the store.
+func NewCachingRequester(r Requester, store CacheStore) (cr Requester) {
+	l := &CachingRequester{
+		requester: r,
+		store:     store,
+	}
+	l.send = l.makeRequest
+	return l
+}
+
+func (l *CachingRequester) makeRequest(ctx context.Context, urlStr string, p ParameterMap, method string, load *payload, opts ...RequestOption) (r Response, err error) {
+	u, err := buildURL(urlStr, p)
+	if err != nil {
+		return nil, err
+	}
+	key := u.String()
+
+	if method != http.MethodGet {
+		r, err = l.requester.makeRequest(ctx, urlStr, p, method, load, opts...)
+		if !isSafeMethod(method) && err == nil {
+			l.store.Delete(key)
+		}
+		return r, err
+	}
+
+	options := buildRequestOptions(nil, opts)
+	reqHeader := http.Header{}
+	options.applyHeaders(reqHeader)
+	reqControl := parseCacheControl(reqHeader)
+
+	if options.stream || reqControl.has("no-store") {
+		return l.requester.makeRequest(ctx, urlStr, p, method, load, opts...)
+	}
+
+	start := time.Now()
+	entry, ok := l.store.Get(key)
+	if ok && !entry.matches(reqHeader) {
+		ok = false
+	}
+
+	if ok && !reqControl.has("no-cache") && entry.usable(reqControl, start) {
+		return entry.response(CacheHit, time.Since(start)), nil
+	}
+
+	if ok {
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			opts = append(opts, WithHeader("If-None-Match", etag))
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			opts = append(opts, WithHeader("If-Modified-Since", modified))
+		}
+	}
+
+	r, err = l.requester.makeRequest(ctx, urlStr, p, method, load, opts...)
+	if code, isStatus := statusCode(err); err != nil && !(isStatus && code == http.StatusNotModified) {
+		return r, err
+	}
+
+	if ok && r.Code() == http.StatusNotModified {
+		r.Body().Close()
+
+		// The stored entry may be in use by other requests so it is
+		// updated on a copy.
+		updated := *entry
+		updated.Header = entry.Header.Clone()
+		updated.revalidate(r.Header(), start)
+		l.store.Set(key, &updated)
+		return updated.response(CacheRevalidated, time.Since(start)), nil
+	}
+
+	// Streamed responses (e.g. when Stream is a default of the wrapped
+	// requester) have no data to store and the body is left to the caller.
+	if isStreamed(r) {
+		return r, err
+	}
+
+	if e := newCacheEntry(r, reqHeader, start); e != nil {
+		l.store.Set(key, e)
+	} else {
+		l.store.Delete(key)
+	}
+
+	if res, isImpl := r.(responseImpl); isImpl {
+		res.cacheStatus = CacheMiss
+		r = res
+	}
+
+	return r, err
+}
+
+// Checks if the body of the response is streamed.
+func isStreamed(r Response) bool {
+	res, isImpl := r.(responseImpl)
+	return isImpl && res.body != nil
+}
+
+// Checks if the method is safe (doesn't change state on the server).
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// Creates an entry from the response - or returns nil if it can't be
+// stored.
+func newCacheEntry(r Response, reqHeader http.Header, responseTime time.Time) (e *CacheEntry) {
+	switch r.Code() {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent:
+	default:
+		return nil
+	}
+
+	control := parseCacheControl(r.Header())
+	if control.has("no-store") {
+		return nil
+	}
+
+	if res, isImpl := r.(responseImpl); isImpl && res.authenticated && !control.has("public") {
+		return nil
+	}
+
+	vary := make(map[string]string)
+	for _, name := range varyHeaders(r.Header()) {
+		if name == "*" {
+			return nil
+		}
+		vary[name] = strings.Join(reqHeader.Values(name), ",")
+	}
+
+	var u string
+	if r.URL() != nil {
+		u = r.URL().String()
+	}
+
+	e = &CacheEntry{
+		Code:     r.Code(),
+		Header:   r.Header().Clone(),
+		Data:     append([]byte(nil), r.Data()...),
+		Proto:    r.Proto(),
+		URL:      u,
+		Vary:     vary,
+		Received: responseTime,
+	}
+	e.Expires = freshUntil(e.Header, control, responseTime)
+
+	if e.Expires.IsZero() && e.Header.Get("ETag") == "" && e.Header.Get("Last-Modified") == "" {
+		return nil
+	}
+
+	return e
+}
+
+// Updates the entry with the headers from a 304 response.
+func (e *CacheEntry) revalidate(h http.Header, responseTime time.Time) {
+	for k, v := range h {
+		switch k {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		e.Header[k] = v
+	}
+
+	e.Expires = freshUntil(e.Header, parseCacheControl(e.Header), responseTime)
+	e.Received = responseTime
+}
+
+// Checks if the entry can be used without revalidation under the
+// directives of the request.
+func (e *CacheEntry) usable(reqControl cacheControl, now time.Time) bool {
+	if maxAge, ok := reqControl.seconds("max-age"); ok && e.age(now) > maxAge {
+		return false
+	}
+
+	expires := e.Expires
+	if expires.IsZero() {
+		return false
+	}
+
+	if minFresh, ok := reqControl.seconds("min-fresh"); ok {
+		expires = expires.Add(-minFresh)
+	}
+
+	// Stale entries are allowed unless the response must be revalidated.
+	if reqControl.has("max-stale") && !parseCacheControl(e.Header).has("must-revalidate") {
+		maxStale, ok := reqControl.seconds("max-stale")
+		if !ok {
+			return true
+		}
+		expires = expires.Add(maxStale)
+	}
+
+	return now.Before(expires)
+}
+
+// Returns the age of the entry including the age it had when received.
+func (e *CacheEntry) age(now time.Time) time.Duration {
+	age, _ := strconv.ParseInt(e.Header.Get("Age"), 10, 64)
+	return now.Sub(e.Received) + time.Duration(age)*time.Second
+}
+
+// Checks if the request headers match the headers the entry varies on.
+func (e *CacheEntry) matches(reqHeader http.Header) bool {
+	for name, value := range e.Vary {
+		if strings.Join(reqHeader.Values(name), ",") != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *CacheEntry) response(status CacheStatus, duration time.Duration) Response {
+	u, _ := url.Parse(e.URL)
+	return responseImpl{
+		data:        append([]byte(nil), e.Data...),
+		code:        e.Code,
+		duration:    duration,
+		header:      e.Header.Clone(),
+		proto:       e.Proto,
+		url:         u,
+		cacheStatus: status,
+	}
+}
+
+// Computes when a response stops being fresh - or the zero time if it must
+// be revalidated before every use.
+func freshUntil(h http.Header, control cacheControl, responseTime time.Time) time.Time {
+	if control.has("no-cache") {
+		return time.Time{}
+	}
+
+	if maxAge, ok := control["max-age"]; ok {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil || seconds <= 0 {
+			return time.Time{}
+		}
+
+		age, _ := strconv.ParseInt(h.Get("Age"), 10, 64)
+		return responseTime.Add(time.Duration(seconds-age) * time.Second)
+	}
+
+	if expires := h.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return time.Time{}
+		}
+
+		if date, err := http.ParseTime(h.Get("Date")); err == nil {
+			return responseTime.Add(t.Sub(date))
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// Gets the canonical names of the headers listed in the Vary header.
+func varyHeaders(h http.Header) (names []string) {
+	for _, value := range h.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// The directives of a Cache-Control header. Directives without a value are
+// stored with the value "".
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) (c cacheControl) {
+	c = make(cacheControl)
+	for _, value := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+
+			name, arg, _ := strings.Cut(directive, "=")
+			c[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return c
+}
+
+func (c cacheControl) has(directive string) bool {
+	_, ok := c[directive]
+	return ok
+}
+
+// Returns the value of a directive given in seconds.
+func (c cacheControl) seconds(directive string) (d time.Duration, ok bool) {
+	seconds, err := strconv.ParseInt(c[directive], 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,270 @@
+package walgo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCacheFresh(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(defaultRequester, NewMemoryCacheStore(10))
+	for i, status := range []CacheStatus{CacheMiss, CacheHit, CacheHit} {
+		res, err := r.Get(server.URL+"/fresh", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.CacheStatus() != status || res.String() != "fresh 1" {
+			t.Fatal("Unexpected response:", i, res.CacheStatus(), res.String())
+		}
+	}
+
+	if _, err := r.PostRaw(server.URL+"/fresh", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := r.Get(server.URL+"/fresh", nil)
+	if err != nil || res.CacheStatus() != CacheMiss {
+		t.Fatal("POST should invalidate the cache:", err, res.CacheStatus())
+	}
+}
+
+func TestCacheRevalidate(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(defaultRequester, NewMemoryCacheStore(10))
+	res, err := r.Get(server.URL+"/etag", nil)
+	if err != nil || res.CacheStatus() != CacheMiss {
+		t.Fatal("First request should miss:", err)
+	}
+
+	res, err = r.Get(server.URL+"/etag", nil, StatusErrors())
+	if err != nil || res.CacheStatus() != CacheRevalidated || res.Code() != http.StatusOK || res.String() != "etag body" {
+		t.Fatal("Second request should be revalidated:", err, res.CacheStatus(), res.String())
+	}
+
+	if server.notModified != 1 {
+		t.Fatal("Server should answer with 304:", server.notModified)
+	}
+}
+
+func TestCacheNotStored(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(defaultRequester, NewMemoryCacheStore(10))
+	for _, path := range []string{"/nostore", "/expires"} {
+		server.hits = 0
+		for i := 0; i < 2; i++ {
+			res, err := r.Get(server.URL+path, nil)
+			if err != nil || res.CacheStatus() != CacheMiss {
+				t.Fatal("Response should not be cached:", path, err)
+			}
+		}
+
+		if server.hits != 2 {
+			t.Fatal("Both requests should hit the server:", path, server.hits)
+		}
+	}
+
+	res, err := r.Get(server.URL+"/fresh", nil, WithHeader("Cache-Control", "no-store"))
+	if err != nil || res.CacheStatus() != CacheNone {
+		t.Fatal("Request with no-store should bypass the cache:", err, res.CacheStatus())
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(defaultRequester, NewMemoryCacheStore(10))
+	expected := []struct {
+		accept string
+		status CacheStatus
+	}{
+		{"text/plain", CacheMiss},
+		{"text/plain", CacheHit},
+		{"application/json", CacheMiss},
+	}
+
+	for _, e := range expected {
+		res, err := r.Get(server.URL+"/vary", nil, WithHeader("Accept", e.accept))
+		if err != nil || res.CacheStatus() != e.status || res.String() != e.accept {
+			t.Fatal("Unexpected response:", e.accept, res.CacheStatus(), res.String())
+		}
+	}
+}
+
+func TestCacheAuthenticated(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(NewRequester(http.DefaultClient, "", "secret"), NewMemoryCacheStore(10))
+	for i := 0; i < 2; i++ {
+		res, err := r.Get(server.URL+"/fresh", nil)
+		if err != nil || res.CacheStatus() != CacheMiss {
+			t.Fatal("Authenticated response should not be cached:", err, res.CacheStatus())
+		}
+	}
+
+	for i, status := range []CacheStatus{CacheMiss, CacheHit} {
+		res, err := r.Get(server.URL+"/public", nil)
+		if err != nil || res.CacheStatus() != status {
+			t.Fatal("Public response should be cached:", i, err, res.CacheStatus())
+		}
+	}
+
+	r = NewCachingRequester(defaultRequester, NewMemoryCacheStore(10))
+	res, err := r.Get(server.URL+"/fresh", nil, WithHeader("Authorization", "Bearer secret"))
+	if err != nil || res.CacheStatus() != CacheMiss {
+		t.Fatal(err)
+	}
+	if res, _ = r.Get(server.URL+"/fresh", nil); res.CacheStatus() != CacheMiss {
+		t.Fatal("Response to a request with credentials should not be served to others.")
+	}
+}
+
+func TestCacheDataCopy(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(defaultRequester, NewMemoryCacheStore(10))
+	for i := 0; i < 2; i++ {
+		res, err := r.Get(server.URL+"/fresh", nil)
+		if err != nil || res.String() != "fresh 1" {
+			t.Fatal("Cached data should not change:", err, res.String())
+		}
+		res.Data()[0] = 'X'
+	}
+}
+
+func TestCacheRequestDirectives(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(defaultRequester, NewMemoryCacheStore(10))
+	for i, c := range []struct {
+		path    string
+		control string
+		status  CacheStatus
+	}{
+		{"/fresh", "", CacheMiss},
+		{"/fresh", "", CacheHit},
+		{"/fresh", "max-age=0", CacheMiss},
+		{"/fresh", "max-age=30", CacheHit},
+		{"/fresh", "min-fresh=120", CacheMiss},
+		{"/stale", "", CacheMiss},
+		{"/stale", "", CacheMiss},
+		{"/stale", "max-stale=300", CacheHit},
+		{"/stale", "max-stale=30", CacheMiss},
+		{"/stale", "max-stale", CacheHit},
+	} {
+		var opts []RequestOption
+		if c.control != "" {
+			opts = append(opts, WithHeader("Cache-Control", c.control))
+		}
+
+		res, err := r.Get(server.URL+c.path, nil, opts...)
+		if err != nil || res.CacheStatus() != c.status {
+			t.Fatalf("(%d) Wrong status %d expected: %d (%v)", i, res.CacheStatus(), c.status, err)
+		}
+	}
+}
+
+func TestMemoryCacheStoreLRU(t *testing.T) {
+	s := NewMemoryCacheStore(2)
+	s.Set("a", &CacheEntry{Code: 1})
+	s.Set("b", &CacheEntry{Code: 2})
+	s.Get("a")
+	s.Set("c", &CacheEntry{Code: 3})
+
+	if _, ok := s.Get("b"); ok {
+		t.Fatal("Least recently used entry should be removed.")
+	}
+
+	if _, ok := s.Get("a"); !ok {
+		t.Fatal("Recently used entry should be kept.")
+	}
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	store, err := NewDiskCacheStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewCachingRequester(defaultRequester, store)
+	r.Get(server.URL+"/fresh", nil)
+
+	// A new requester using the same directory sees the entry.
+	r = NewCachingRequester(defaultRequester, store)
+	res, err := r.Get(server.URL+"/fresh", nil)
+	if err != nil || res.CacheStatus() != CacheHit || res.String() != "fresh 1" {
+		t.Fatal("Entry should be read from disk:", err, res.CacheStatus(), res.String())
+	}
+
+	store.Delete(server.URL + "/fresh")
+	if _, ok := store.Get(server.URL + "/fresh"); ok {
+		t.Fatal("Entry should be deleted.")
+	}
+}
+
+func TestDiskCacheStoreLRU(t *testing.T) {
+	s, err := NewDiskCacheStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The modification times are set explicitly as they may be too coarse
+	// to order writes made right after each other.
+	past := time.Now().Add(-time.Hour)
+	s.Set("a", &CacheEntry{})
+	os.Chtimes(s.path("a"), past, past)
+	s.Set("b", &CacheEntry{})
+	os.Chtimes(s.path("b"), past.Add(time.Minute), past.Add(time.Minute))
+
+	s.Get("a")
+	s.Set("c", &CacheEntry{})
+
+	if _, ok := s.Get("b"); ok {
+		t.Fatal("Least recently used entry should be removed.")
+	}
+
+	if _, ok := s.Get("a"); !ok {
+		t.Fatal("Recently used entry should be kept.")
+	}
+
+	if _, ok := s.Get("c"); !ok {
+		t.Fatal("New entry should be kept.")
+	}
+}
+
+func TestCacheStreamedDefault(t *testing.T) {
+	server := newCacheServer()
+	defer server.Close()
+
+	r := NewCachingRequester(NewRequester(http.DefaultClient, "", "", Stream()), NewMemoryCacheStore(10))
+	for i := 1; i <= 2; i++ {
+		res, err := r.Get(server.URL+"/fresh", nil)
+		if err != nil || res.CacheStatus() != CacheNone {
+			t.Fatal("Streamed response should not be cached:", err, res.CacheStatus())
+		}
+
+		body := res.Body()
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil || string(data) != fmt.Sprintf("fresh %d", i) {
+			t.Fatal("Unexpected body:", string(data), err)
+		}
+	}
+}
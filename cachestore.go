@@ -0,0 +1,198 @@
+package walgo
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryCacheStore is a CacheStore keeping the entries in memory. When it
+// is full the least recently used entry is removed.
+type MemoryCacheStore struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	lock       *sync.Mutex
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCacheStore creates a new in-memory store holding at most the
+// given number of entries.
+func NewMemoryCacheStore(maxEntries int) (s *MemoryCacheStore) {
+	return &MemoryCacheStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		lock:       &sync.Mutex{},
+	}
+}
+
+// Get returns the entry and marks it as the most recently used.
+func (s *MemoryCacheStore) Get(key string) (e *CacheEntry, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	s.order.MoveToFront(element)
+	return element.Value.(*memoryCacheItem).entry, true
+}
+
+// Set stores the entry and removes the least recently used entry if the
+// store is full.
+func (s *MemoryCacheStore) Set(key string, e *CacheEntry) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		element.Value.(*memoryCacheItem).entry = e
+		s.order.MoveToFront(element)
+		return
+	}
+
+	s.entries[key] = s.order.PushFront(&memoryCacheItem{key: key, entry: e})
+
+	for s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete removes the entry.
+func (s *MemoryCacheStore) Delete(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+		delete(s.entries, key)
+	}
+}
+
+// DiskCacheStore is a CacheStore keeping each entry as a JSON file in a
+// directory. Failing to read or write an entry is treated as a cache miss.
+// When it is full the least recently used entries are removed, using the
+// modification times of the files so the limit holds for every store
+// sharing the directory.
+type DiskCacheStore struct {
+	dir        string
+	maxEntries int
+}
+
+// The prefix of the temporary files entries are written to.
+const diskCacheTempPrefix = "tmp-"
+
+// NewDiskCacheStore creates a new store using the given directory - it is
+// created if it doesn't exist. The store holds at most the given number of
+// entries, or any number if it is 0 or less.
+func NewDiskCacheStore(dir string, maxEntries int) (s *DiskCacheStore, err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &DiskCacheStore{dir: dir, maxEntries: maxEntries}, nil
+}
+
+// The file name is the hash of the key so any key is a valid name.
+func (s *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get reads the entry from disk and marks it as the most recently used.
+func (s *DiskCacheStore) Get(key string) (e *CacheEntry, ok bool) {
+	path := s.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	e = &CacheEntry{}
+	if err = json.Unmarshal(data, e); err != nil {
+		return nil, false
+	}
+
+	if s.maxEntries > 0 {
+		now := time.Now()
+		os.Chtimes(path, now, now)
+	}
+
+	return e, true
+}
+
+// Set writes the entry to disk and removes the least recently used entries
+// if the store is full. The entry is written to a temporary file which is
+// then renamed so readers never see a partial entry.
+func (s *DiskCacheStore) Set(key string, e *CacheEntry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	f, err := ioutil.TempFile(s.dir, diskCacheTempPrefix)
+	if err != nil {
+		return
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil || os.Rename(f.Name(), s.path(key)) != nil {
+		os.Remove(f.Name())
+		return
+	}
+
+	if s.maxEntries > 0 {
+		s.prune()
+	}
+}
+
+// Removes the least recently used entries beyond the max number of entries.
+func (s *DiskCacheStore) prune() {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+
+	entries := files[:0]
+	for _, f := range files {
+		if f.Mode().IsRegular() && !strings.HasPrefix(f.Name(), diskCacheTempPrefix) {
+			entries = append(entries, f)
+		}
+	}
+
+	if len(entries) <= s.maxEntries {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	for _, f := range entries[:len(entries)-s.maxEntries] {
+		os.Remove(filepath.Join(s.dir, f.Name()))
+	}
+}
+
+// Delete removes the entry from disk.
+func (s *DiskCacheStore) Delete(key string) {
+	os.Remove(s.path(key))
+}
@@ -0,0 +1,368 @@
+package walgo
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	// CircuitOpenErr is returned from the CircuitBreakerRequester while the
+	// circuit is open.
+	CircuitOpenErr = errors.New("Circuit breaker is open.")
+)
+
+// The number of buckets the rolling window is divided into.
+const circuitBuckets = 10
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through and counts the failures.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen fails requests without sending them.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a limited number of trial requests through to
+	// decide whether to close or open the circuit again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerConfig holds the settings for a CircuitBreakerRequester.
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures opens the circuit after this many failures in a
+	// row. 0 disables the rule.
+	ConsecutiveFailures int
+
+	// FailureRatio opens the circuit when the ratio of failed requests in
+	// the window reaches it - if at least MinRequests were made. 0
+	// disables the rule.
+	FailureRatio float64
+	MinRequests  int
+
+	// Window is the rolling window used for the failure ratio. If it is 0,
+	// one minute is used.
+	Window time.Duration
+
+	// OpenTimeout is how long the circuit stays open before trial requests
+	// are let through. If it is 0, 30 seconds is used.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of trial requests let through in the
+	// half-open state. The circuit closes when they all succeed and opens
+	// on the first failure. If it is 0, 1 is used.
+	HalfOpenRequests int
+
+	// IsIgnored picks the outcomes that are neither successes nor
+	// failures. They don't count towards the window or break a streak of
+	// failures, and an ignored trial request in the half-open state frees
+	// its slot for another. If it is nil errors that don't come from the
+	// server are ignored, e.g. cancelled requests, requests stopped by a
+	// rate limit or another open circuit and requests that can't be built
+	// or authenticated.
+	IsIgnored func(r Response, err error) bool
+
+	// IsFailure classifies the outcome of a request. If it is nil
+	// connection errors, timeouts and 5xx responses are failures.
+	IsFailure func(r Response, err error) bool
+
+	// PerHost keeps a separate circuit for each host.
+	PerHost bool
+
+	// OnStateChange is called (if not nil) when a circuit changes state.
+	// The host is "" unless PerHost is set. It is called while holding a
+	// lock and must not make requests through the requester.
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// CircuitBreakerRequester is a Requester that stops sending requests to
+// the internal Requester while it is failing.
+type CircuitBreakerRequester struct {
+	requestMethods
+
+	requester Requester
+	config    CircuitBreakerConfig
+	circuits  map[string]*circuit
+	lock      *sync.Mutex
+	now       func() time.Time
+}
+
+// Holds the state of a single circuit. Outcomes are counted in buckets
+// covering the rolling window.
+type circuit struct {
+	state       CircuitState
+	generation  int
+	openedAt    time.Time
+	consecutive int
+
+	buckets     [circuitBuckets]circuitBucket
+	bucketIndex int
+	bucketStart time.Time
+
+	trials    int
+	successes int
+}
+
+// The outcome of a request as counted by the circuit.
+type circuitOutcome int
+
+const (
+	circuitSuccess circuitOutcome = iota
+	circuitFailure
+	circuitIgnored
+)
+
+type circuitBucket struct {
+	requests int
+	failures int
+}
+
+// NewCircuitBreakerRequester creates a new Requester forwarding requests to
+// the given Requester until the circuit opens.
+func NewCircuitBreakerRequester(r Requester, config CircuitBreakerConfig) (cr Requester) {
+	if config.Window == 0 {
+		config.Window = time.Minute
+	}
+	if config.OpenTimeout == 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenRequests == 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.IsIgnored == nil {
+		config.IsIgnored = isCircuitIgnored
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isCircuitFailure
+	}
+
+	l := &CircuitBreakerRequester{
+		requester: r,
+		config:    config,
+		circuits:  make(map[string]*circuit),
+		lock:      &sync.Mutex{},
+		now:       time.Now,
+	}
+	l.send = l.makeRequest
+	return l
+}
+
+// State returns the state of the circuit for the host - use "" unless
+// PerHost is set.
+func (l *CircuitBreakerRequester) State(host string) (s CircuitState) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if c, ok := l.circuits[host]; ok {
+		l.update(host, c, l.now())
+		return c.state
+	}
+	return CircuitClosed
+}
+
+func (l *CircuitBreakerRequester) makeRequest(ctx context.Context, urlStr string, p ParameterMap, method string, load *payload, opts ...RequestOption) (r Response, err error) {
+	host := ""
+	if l.config.PerHost {
+		u, err := url.Parse(urlStr)
+		if err != nil {
+			return nil, err
+		}
+		host = u.Host
+	}
+
+	generation, err := l.allow(host)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err = l.requester.makeRequest(ctx, urlStr, p, method, load, opts...)
+	outcome := circuitSuccess
+	if l.config.IsIgnored(r, err) {
+		outcome = circuitIgnored
+	} else if l.config.IsFailure(r, err) {
+		outcome = circuitFailure
+	}
+
+	l.record(host, generation, outcome)
+	return r, err
+}
+
+// Checks if a request may be sent and returns the generation of the
+// circuit so the outcome is only counted if the state hasn't changed.
+func (l *CircuitBreakerRequester) allow(host string) (generation int, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	c, ok := l.circuits[host]
+	if !ok {
+		c = &circuit{}
+		l.circuits[host] = c
+	}
+
+	l.update(host, c, l.now())
+
+	switch c.state {
+	case CircuitOpen:
+		return 0, CircuitOpenErr
+	case CircuitHalfOpen:
+		if c.trials >= l.config.HalfOpenRequests {
+			return 0, CircuitOpenErr
+		}
+		c.trials++
+	}
+
+	return c.generation, nil
+}
+
+// Counts the outcome of a request and changes the state if needed.
+func (l *CircuitBreakerRequester) record(host string, generation int, outcome circuitOutcome) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	c := l.circuits[host]
+	if c.generation != generation {
+		return
+	}
+
+	if outcome == circuitIgnored {
+		if c.state == CircuitHalfOpen {
+			c.trials--
+		}
+		return
+	}
+
+	now := l.now()
+	failed := outcome == circuitFailure
+
+	if c.state == CircuitHalfOpen {
+		if failed {
+			l.setState(host, c, CircuitOpen, now)
+			return
+		}
+
+		c.successes++
+		if c.successes >= l.config.HalfOpenRequests {
+			l.setState(host, c, CircuitClosed, now)
+		}
+		return
+	}
+
+	l.rotate(c, now)
+	bucket := &c.buckets[c.bucketIndex]
+	bucket.requests++
+	if failed {
+		bucket.failures++
+		c.consecutive++
+	} else {
+		c.consecutive = 0
+	}
+
+	if l.config.ConsecutiveFailures > 0 && c.consecutive >= l.config.ConsecutiveFailures {
+		l.setState(host, c, CircuitOpen, now)
+		return
+	}
+
+	if l.config.FailureRatio > 0 {
+		requests, failures := 0, 0
+		for _, b := range c.buckets {
+			requests += b.requests
+			failures += b.failures
+		}
+
+		if requests >= l.config.MinRequests && float64(failures)/float64(requests) >= l.config.FailureRatio {
+			l.setState(host, c, CircuitOpen, now)
+		}
+	}
+}
+
+// Moves an open circuit to half-open when the open timeout has passed.
+func (l *CircuitBreakerRequester) update(host string, c *circuit, now time.Time) {
+	if c.state == CircuitOpen && now.Sub(c.openedAt) >= l.config.OpenTimeout {
+		l.setState(host, c, CircuitHalfOpen, now)
+	}
+}
+
+// Advances the rolling window clearing the buckets that have expired.
+func (l *CircuitBreakerRequester) rotate(c *circuit, now time.Time) {
+	size := l.config.Window / circuitBuckets
+
+	if c.bucketStart.IsZero() {
+		c.bucketStart = now
+	}
+
+	for i := 0; i < circuitBuckets && now.Sub(c.bucketStart) >= size; i++ {
+		c.bucketIndex = (c.bucketIndex + 1) % circuitBuckets
+		c.buckets[c.bucketIndex] = circuitBucket{}
+		c.bucketStart = c.bucketStart.Add(size)
+	}
+
+	if now.Sub(c.bucketStart) >= size {
+		c.bucketStart = now
+	}
+}
+
+func (l *CircuitBreakerRequester) setState(host string, c *circuit, state CircuitState, now time.Time) {
+	from := c.state
+	c.state = state
+	c.generation++
+	c.trials = 0
+	c.successes = 0
+
+	switch state {
+	case CircuitOpen:
+		c.openedAt = now
+	case CircuitClosed:
+		c.consecutive = 0
+		c.buckets = [circuitBuckets]circuitBucket{}
+		c.bucketStart = time.Time{}
+	}
+
+	if l.config.OnStateChange != nil {
+		l.config.OnStateChange(host, from, state)
+	}
+}
+
+// The default classification of ignored outcomes: errors that don't come
+// from the server.
+func isCircuitIgnored(r Response, err error) bool {
+	if _, ok := statusCode(err); ok || err == nil {
+		return false
+	}
+	return !isUnreachable(err)
+}
+
+// The default failure classification: connection errors, timeouts and 5xx
+// responses.
+func isCircuitFailure(r Response, err error) bool {
+	if code, ok := statusCode(err); ok {
+		return code >= 500
+	}
+
+	if err != nil {
+		return isUnreachable(err)
+	}
+
+	return r.Code() >= 500
+}
+
+// Checks if the error is from failing to reach the server - timeouts
+// included so a server that hangs opens the circuit.
+func isUnreachable(err error) bool {
+	return isConnectionError(err) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,241 @@
+package walgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitConsecutiveFailures(t *testing.T) {
+	failing := true
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	var changes []CircuitState
+	r := NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         50 * time.Millisecond,
+		OnStateChange: func(host string, from, to CircuitState) {
+			changes = append(changes, to)
+		},
+	})
+
+	now := time.Now()
+	r.(*CircuitBreakerRequester).now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		_, err := r.Get(server.URL, nil)
+		if i < 3 && err != nil {
+			t.Fatal("Request should be sent:", i, err)
+		}
+		if i >= 3 && err != CircuitOpenErr {
+			t.Fatal("Circuit should be open:", i, err)
+		}
+	}
+
+	if hits != 3 {
+		t.Fatal("Open circuit should not send requests:", hits)
+	}
+
+	now = now.Add(60 * time.Millisecond)
+	if state := r.(*CircuitBreakerRequester).State(""); state != CircuitHalfOpen {
+		t.Fatal("Circuit should be half-open:", state)
+	}
+
+	// A failing trial opens the circuit again.
+	r.Get(server.URL, nil)
+	if _, err := r.Get(server.URL, nil); err != CircuitOpenErr {
+		t.Fatal("Failed trial should open the circuit:", err)
+	}
+
+	now = now.Add(60 * time.Millisecond)
+	failing = false
+	if _, err := r.Get(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(changes) != len(expected) {
+		t.Fatal("Unexpected state changes:", changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Fatal("Unexpected state changes:", changes)
+		}
+	}
+}
+
+func TestCircuitFailureRatio(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits%2 == 0 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	r := NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{
+		FailureRatio: 0.5,
+		MinRequests:  6,
+	})
+
+	for i := 0; i < 6; i++ {
+		if _, err := r.Get(server.URL, nil); err != nil {
+			t.Fatal("Request should be sent:", i, err)
+		}
+	}
+
+	if _, err := r.Get(server.URL, nil); err != CircuitOpenErr {
+		t.Fatal("Circuit should open at 50% failures:", err)
+	}
+}
+
+func TestCircuitPerHost(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer good.Close()
+
+	r := NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{ConsecutiveFailures: 1, PerHost: true})
+	r.Get(bad.URL, nil)
+
+	if _, err := r.Get(bad.URL, nil); err != CircuitOpenErr {
+		t.Fatal("Circuit for failing host should be open:", err)
+	}
+
+	if _, err := r.Get(good.URL, nil); err != nil {
+		t.Fatal("Other hosts should not be affected:", err)
+	}
+
+	u, _ := url.Parse(bad.URL)
+	if r.(*CircuitBreakerRequester).State(u.Host) != CircuitOpen {
+		t.Fatal("State should be reported per host.")
+	}
+}
+
+func TestCircuitClassification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	r := NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{ConsecutiveFailures: 1})
+	for i := 0; i < 3; i++ {
+		if _, err := r.Get(server.URL, nil, StatusErrors()); !IsNotFound(err) {
+			t.Fatal("Client errors should not open the circuit:", err)
+		}
+	}
+
+	r = NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		IsFailure: func(r Response, err error) bool {
+			return err != nil || r.Code() == http.StatusNotFound
+		},
+	})
+	r.Get(server.URL, nil)
+	if _, err := r.Get(server.URL, nil); err != CircuitOpenErr {
+		t.Fatal("Custom classification should open the circuit:", err)
+	}
+}
+
+func TestCircuitIgnoredOutcomes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{
+		ConsecutiveFailures: 2,
+		OpenTimeout:         50 * time.Millisecond,
+	})
+	breaker := r.(*CircuitBreakerRequester)
+
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	// A cancelled request doesn't break the streak of failures.
+	r.Get(server.URL, nil)
+	r.GetContext(cancelled, server.URL, nil)
+	r.Get(server.URL, nil)
+	if state := breaker.State(""); state != CircuitOpen {
+		t.Fatal("Circuit should be open:", state)
+	}
+
+	// A cancelled trial neither closes the circuit nor uses up the trial.
+	now = now.Add(60 * time.Millisecond)
+	r.GetContext(cancelled, server.URL, nil)
+	if state := breaker.State(""); state != CircuitHalfOpen {
+		t.Fatal("Cancelled trial should leave the circuit half-open:", state)
+	}
+
+	if _, err := r.Get(server.URL, nil); err == CircuitOpenErr {
+		t.Fatal("Cancelled trial should free its slot.")
+	}
+	if state := breaker.State(""); state != CircuitOpen {
+		t.Fatal("Failed trial should open the circuit:", state)
+	}
+}
+
+func TestCircuitTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	r := NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{ConsecutiveFailures: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	r.GetContext(ctx, server.URL, nil)
+	if state := r.(*CircuitBreakerRequester).State(""); state != CircuitOpen {
+		t.Fatal("Hanging server should open the circuit:", state)
+	}
+}
+
+func TestCircuitLocalErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	failingAuth := AuthenticatorFunc(func(req *http.Request) error {
+		return errors.New("no token")
+	})
+
+	r := NewCircuitBreakerRequester(defaultRequester, CircuitBreakerConfig{ConsecutiveFailures: 1})
+	breaker := r.(*CircuitBreakerRequester)
+
+	r.Get("://bad-url", nil)
+	r.Get(server.URL, nil, WithAuthenticator(failingAuth))
+	r.Get(server.URL, nil, MaxBodySize(10))
+	if state := breaker.State(""); state != CircuitClosed {
+		t.Fatal("Local errors should not open the circuit:", state)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	r.Get(closed.URL, nil)
+	if state := breaker.State(""); state != CircuitOpen {
+		t.Fatal("Connection errors should open the circuit:", state)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/mbanzon/walgo
+
+go 1.18
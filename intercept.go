@@ -0,0 +1,127 @@
+package walgo
+
+import (
+	"context"
+)
+
+// Request is an outgoing request as seen by an Interceptor. The fields can
+// be changed before handing the request on - eg. adding RequestOptions to
+// set headers.
+type Request struct {
+	// Context is the context of the request.
+	Context context.Context
+
+	// Method is the HTTP method of the request.
+	Method string
+
+	// URL is the URL of the request without the parameters.
+	URL string
+
+	// Params are the parameters added to the query string.
+	Params ParameterMap
+
+	// Options are the RequestOptions given for the request.
+	Options []RequestOption
+
+	payload *payload
+}
+
+// RoundTrip sends a request and returns the response.
+type RoundTrip func(req *Request) (r Response, err error)
+
+// Interceptor wraps a RoundTrip with additional behaviour. It can change
+// the request before calling next, inspect or replace the response, call
+// next several times or not at all.
+type Interceptor func(next RoundTrip) RoundTrip
+
+// InterceptRequester is a Requester that passes every request through a
+// chain of Interceptors before handing it to the internal Requester.
+type InterceptRequester struct {
+	requestMethods
+
+	chain RoundTrip
+}
+
+// NewInterceptRequester creates a new Requester sending requests through
+// the interceptors and then to the given Requester. The first interceptor
+// is the outermost - it sees the request first and the response last.
+func NewInterceptRequester(r Requester, interceptors ...Interceptor) (ir Requester) {
+	chain := RoundTrip(func(req *Request) (Response, error) {
+		return r.makeRequest(req.Context, req.URL, req.Params, req.Method, req.payload, req.Options...)
+	})
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		chain = interceptors[i](chain)
+	}
+
+	return newInterceptRequester(chain)
+}
+
+func newInterceptRequester(chain RoundTrip) *InterceptRequester {
+	l := &InterceptRequester{chain: chain}
+	l.send = l.makeRequest
+	return l
+}
+
+// WrapperInterceptor creates an Interceptor from a function wrapping a
+// Requester - eg. NewRetryRequester or NewRateLimitRequester - so they can
+// be used in a chain of interceptors. The wrapper is called once.
+func WrapperInterceptor(wrap func(r Requester) Requester) Interceptor {
+	return func(next RoundTrip) RoundTrip {
+		wrapped := wrap(newInterceptRequester(next))
+		return func(req *Request) (Response, error) {
+			return wrapped.makeRequest(req.Context, req.URL, req.Params, req.Method, req.payload, req.Options...)
+		}
+	}
+}
+
+// ContentType returns the content type of the request body - or "" if the
+// request has no body.
+func (req *Request) ContentType() string {
+	if req.payload == nil {
+		return ""
+	}
+	return req.payload.getContentType()
+}
+
+// Data returns the request body. It is nil if the request has no body or
+// if the body is streamed from a reader or files.
+func (req *Request) Data() []byte {
+	if req.payload == nil {
+		return nil
+	}
+	return req.payload.getData()
+}
+
+// Streamed reports whether the request body is streamed. Streamed bodies
+// can only be sent once.
+func (req *Request) Streamed() bool {
+	return !req.payload.replayable()
+}
+
+// SetBody replaces the body of the request. A nil body removes it.
+func (req *Request) SetBody(body Body) (err error) {
+	if body == nil {
+		req.payload = nil
+		return nil
+	}
+
+	p, err := body.build()
+	if err != nil {
+		return err
+	}
+
+	req.payload = p
+	return nil
+}
+
+func (l *InterceptRequester) makeRequest(ctx context.Context, url string, p ParameterMap, method string, load *payload, opts ...RequestOption) (r Response, err error) {
+	return l.chain(&Request{
+		Context: ctx,
+		Method:  method,
+		URL:     url,
+		Params:  p,
+		Options: opts,
+		payload: load,
+	})
+}
@@ -0,0 +1,77 @@
+package walgo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestInterceptRequester(t *testing.T) {
+	var headers http.Header
+	server := newHeaderServer(&headers)
+	defer server.Close()
+
+	var calls []string
+	logging := func(name string) Interceptor {
+		return func(next RoundTrip) RoundTrip {
+			return func(req *Request) (Response, error) {
+				calls = append(calls, name+" "+req.Method)
+				res, err := next(req)
+				calls = append(calls, name+" done")
+				return res, err
+			}
+		}
+	}
+
+	auth := func(next RoundTrip) RoundTrip {
+		return func(req *Request) (Response, error) {
+			req.Options = append(req.Options, WithHeader("X-Api-Key", "secret"))
+			if req.ContentType() != octetStreamContentType || string(req.Data()) != "data" {
+				t.Error("Unexpected body:", req.ContentType(), string(req.Data()))
+			}
+			return next(req)
+		}
+	}
+
+	r := NewInterceptRequester(defaultRequester, logging("outer"), auth, logging("inner"))
+	_, err := r.PostRaw(server.URL, nil, []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("X-Api-Key") != "secret" {
+		t.Fatal("Interceptor header not sent.")
+	}
+
+	expected := []string{"outer POST", "inner POST", "inner done", "outer done"}
+	if len(calls) != len(expected) {
+		t.Fatal("Unexpected calls:", calls)
+	}
+	for i := range calls {
+		if calls[i] != expected[i] {
+			t.Fatal("Unexpected calls:", calls)
+		}
+	}
+}
+
+func TestWrapperInterceptor(t *testing.T) {
+	server, hits := newFailingServer(1, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	r := NewInterceptRequester(defaultRequester,
+		WrapperInterceptor(func(r Requester) Requester {
+			return NewRateLimitRequester(r, 1, time.Hour)
+		}),
+		WrapperInterceptor(func(r Requester) Requester {
+			return NewRetryRequester(r, testRetryPolicy)
+		}))
+
+	res, err := r.Get(server.URL, nil)
+	if err != nil || res.Code() != http.StatusOK || *hits != 2 {
+		t.Fatal("Request should be retried:", err, *hits)
+	}
+
+	if _, err = r.Get(server.URL, nil); err != RateLimitExceededErr {
+		t.Fatal("Second request should be rate limited:", err)
+	}
+}
@@ -0,0 +1,215 @@
+package walgo
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	forwardedForHeader = "X-Forwarded-For"
+	realIPHeader       = "X-Real-Ip"
+	forwardedHeader    = "Forwarded"
+)
+
+// IPRatePolicy rate limits using the clients IP address as client
+// identification.
+//
+// By default the address of the peer is used. Behind proxies or load
+// balancers TrustedProxies must list their networks. When the peer is a
+// trusted proxy the forwarding header is walked from right to left,
+// skipping the trusted proxies, and the first address that isn't trusted
+// is the client. Entries left of that are never used, so clients can't
+// spoof their address by sending the header themselves.
+//
+// By default each address is a client. Setting IPv6Prefix aggregates the
+// addresses into networks so clients holding a whole IPv6 network, usually
+// a /64, can't get around the limit by rotating addresses. IPv4-mapped
+// IPv6 addresses are treated as IPv4 addresses.
+type IPRatePolicy struct {
+	// TrustedProxies are the networks of the proxies whose forwarding
+	// headers are trusted.
+	TrustedProxies []*net.IPNet
+
+	// Header holds the forwarding chain. It can be X-Forwarded-For (the
+	// default), X-Real-IP, Forwarded or any other header holding a comma
+	// separated list of addresses.
+	Header string
+
+	// IPv4Prefix is the prefix length IPv4 addresses are aggregated by. If
+	// it is 0, 32 is used so each address is a client.
+	IPv4Prefix int
+
+	// IPv6Prefix is the prefix length IPv6 addresses are aggregated by. If
+	// it is 0, 128 is used so each address is a client.
+	IPv6Prefix int
+
+	// PrefixOverrides sets other prefix lengths for addresses in specific
+	// networks. The most specific matching network is used.
+	PrefixOverrides []PrefixOverride
+}
+
+// PrefixOverride aggregates the addresses in Network by Prefix instead of
+// the default prefix length of the IPRatePolicy.
+type PrefixOverride struct {
+	Network *net.IPNet
+	Prefix  int
+}
+
+// ParseTrustedProxies parses networks in CIDR notation, e.g. "10.0.0.0/8",
+// for use as IPRatePolicy.TrustedProxies. Single addresses are accepted as
+// well.
+func ParseTrustedProxies(cidrs ...string) (proxies []*net.IPNet, err error) {
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+// GetClient implements getting the client id string from the request
+// remote IP address value - or from the forwarding header if the request
+// came through trusted proxies.
+func (p IPRatePolicy) GetClient(r *http.Request) (client string, err error) {
+	client, _, err = net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return client, err
+	}
+
+	ip := net.ParseIP(client)
+	if ip == nil {
+		return client, nil
+	}
+
+	if !p.trusted(ip) {
+		return p.aggregate(ip), nil
+	}
+
+	chain := p.chain(r)
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := parseForwardedIP(chain[i])
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+		if !p.trusted(ip) {
+			break
+		}
+	}
+
+	return p.aggregate(ip), nil
+}
+
+// Returns the network of the address by the configured prefix lengths, or
+// just the address if the prefix covers all of it.
+func (p IPRatePolicy) aggregate(ip net.IP) string {
+	bits, prefix := 128, p.IPv6Prefix
+	if prefix == 0 {
+		prefix = 128
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits, prefix = ip4, 32, p.IPv4Prefix
+		if prefix == 0 {
+			prefix = 32
+		}
+	}
+
+	specific := -1
+	for _, o := range p.PrefixOverrides {
+		if ones, _ := o.Network.Mask.Size(); o.Network.Contains(ip) && ones > specific {
+			specific, prefix = ones, o.Prefix
+		}
+	}
+
+	if prefix >= bits {
+		return ip.String()
+	}
+
+	mask := net.CIDRMask(prefix, bits)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// Checks if the address belongs to a trusted proxy.
+func (p IPRatePolicy) trusted(ip net.IP) bool {
+	for _, network := range p.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the addresses in the forwarding header, the client first.
+func (p IPRatePolicy) chain(r *http.Request) (chain []string) {
+	header := http.CanonicalHeaderKey(p.Header)
+	if header == "" {
+		header = forwardedForHeader
+	}
+
+	switch header {
+	case realIPHeader:
+		if value := r.Header.Get(realIPHeader); value != "" {
+			chain = []string{value}
+		}
+	case forwardedHeader:
+		chain = forwardedFor(r.Header.Values(forwardedHeader))
+	default:
+		for _, value := range r.Header.Values(header) {
+			for _, address := range strings.Split(value, ",") {
+				chain = append(chain, strings.TrimSpace(address))
+			}
+		}
+	}
+
+	return chain
+}
+
+// Returns the "for" parameter of each element of the RFC 7239 Forwarded
+// header. Elements without one give an empty entry so the chain stops
+// there.
+func forwardedFor(values []string) (chain []string) {
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			address := ""
+			for _, pair := range strings.Split(element, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					address = strings.Trim(v, `"`)
+				}
+			}
+			chain = append(chain, address)
+		}
+	}
+
+	return chain
+}
+
+// Parses an address from a forwarding header. It may have a port and
+// IPv6 addresses may be in brackets. Obfuscated identifiers and "unknown"
+// give nil.
+func parseForwardedIP(address string) net.IP {
+	if strings.HasPrefix(address, "[") {
+		end := strings.Index(address, "]")
+		if end < 0 {
+			return nil
+		}
+		address = address[1:end]
+	} else if strings.Count(address, ":") == 1 {
+		address, _, _ = strings.Cut(address, ":")
+	}
+
+	return net.ParseIP(address)
+}
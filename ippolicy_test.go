@@ -0,0 +1,155 @@
+package walgo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newProxiedRequest(peer string, headers map[string][]string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.RemoteAddr = peer
+	for k, values := range headers {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	return req
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8", "192.168.1.1", "fd00::/8", "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(proxies) != 4 || proxies[1].String() != "192.168.1.1/32" || proxies[3].String() != "::1/128" {
+		t.Fatal("Unexpected proxies:", proxies)
+	}
+
+	if _, err = ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Fatal("Invalid network should fail.")
+	}
+}
+
+func TestTrustedProxyIPRatePolicy(t *testing.T) {
+	proxies, _ := ParseTrustedProxies("10.0.0.0/8", "fd00::/8")
+
+	for i, c := range []struct {
+		header   string
+		peer     string
+		headers  map[string][]string
+		expected string
+	}{
+		// Without trusted proxies the peer is the client.
+		{"", "203.0.113.7:1234", map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.7"},
+		// A trusted proxy forwarding for the client.
+		{"", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+		// A client spoofing the header through the proxy.
+		{"", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1"}}, "198.51.100.1"},
+		// A chain of trusted proxies spread over several headers.
+		{"", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1", "10.0.0.2"}}, "198.51.100.1"},
+		// Only trusted proxies in the chain.
+		{"", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		// No header from a trusted proxy.
+		{"", "10.0.0.1:1234", nil, "10.0.0.1"},
+		// Garbage in the chain stops the walk.
+		{"", "10.0.0.1:1234", map[string][]string{"X-Forwarded-For": {"198.51.100.1, garbage"}}, "10.0.0.1"},
+		// Untrusted peer sending X-Real-IP.
+		{"X-Real-IP", "203.0.113.7:1234", map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, "203.0.113.7"},
+		// Trusted peer sending X-Real-IP.
+		{"X-Real-IP", "[fd00::1]:1234", map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, "198.51.100.1"},
+		// Forwarded with ports, quoting and IPv6.
+		{"Forwarded", "10.0.0.1:1234", map[string][]string{"Forwarded": {`for=1.2.3.4, for="[2001:db8::1]:4711";proto=https`, "for=10.0.0.2:80"}}, "2001:db8::1"},
+		// Forwarded with an element missing the for parameter.
+		{"Forwarded", "10.0.0.1:1234", map[string][]string{"Forwarded": {"for=198.51.100.1, proto=https"}}, "10.0.0.1"},
+		// Obfuscated identifiers are not addresses.
+		{"Forwarded", "10.0.0.1:1234", map[string][]string{"Forwarded": {"for=_hidden"}}, "10.0.0.1"},
+	} {
+		p := IPRatePolicy{TrustedProxies: proxies, Header: c.header}
+		if i == 0 {
+			p.TrustedProxies = nil
+		}
+
+		client, err := p.GetClient(newProxiedRequest(c.peer, c.headers))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if client != c.expected {
+			t.Fatalf("(%d) Wrong client %s expected: %s", i, client, c.expected)
+		}
+	}
+}
+
+func TestTrustedProxyRateLimit(t *testing.T) {
+	proxies, _ := ParseTrustedProxies("10.0.0.0/8")
+	h := &hitCountHandler{}
+	r := NewRateLimiter(1, time.Hour, IPRatePolicy{TrustedProxies: proxies}, h)
+
+	for i, spoofed := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newProxiedRequest("10.0.0.1:1234", map[string][]string{
+			"X-Forwarded-For": {spoofed + ", 198.51.100.1"},
+		}))
+
+		if i > 0 && w.Code != http.StatusTooManyRequests {
+			t.Fatalf("(%d) Spoofed header should not reset the limit: %d", i, w.Code)
+		}
+	}
+
+	if h.hitCount != 1 {
+		t.Fatal("Wrong hit count:", h.hitCount)
+	}
+}
+
+func TestIPRatePolicyPrefixes(t *testing.T) {
+	_, carrier, _ := net.ParseCIDR("2001:db8:aa::/48")
+	_, shared, _ := net.ParseCIDR("2001:db8:aa:bb::/64")
+	_, office, _ := net.ParseCIDR("192.0.2.0/24")
+
+	for i, c := range []struct {
+		policy   IPRatePolicy
+		peer     string
+		expected string
+	}{
+		{IPRatePolicy{}, "192.0.2.10:1234", "192.0.2.10"},
+		{IPRatePolicy{}, "[2001:db8::1]:1234", "2001:db8::1"},
+		{IPRatePolicy{IPv6Prefix: 64}, "[2001:db8::ffff:1]:1234", "2001:db8::/64"},
+		{IPRatePolicy{}, "[::ffff:192.0.2.10]:1234", "192.0.2.10"},
+		{IPRatePolicy{IPv6Prefix: 56}, "[2001:db8:0:ff::1]:1234", "2001:db8::/56"},
+		{IPRatePolicy{IPv6Prefix: 128}, "[2001:db8::1]:1234", "2001:db8::1"},
+		{IPRatePolicy{IPv4Prefix: 24}, "192.0.2.10:1234", "192.0.2.0/24"},
+		{IPRatePolicy{IPv4Prefix: 24}, "[::ffff:192.0.2.10]:1234", "192.0.2.0/24"},
+		{IPRatePolicy{PrefixOverrides: []PrefixOverride{{carrier, 56}, {shared, 128}}}, "[2001:db8:aa:1::1]:1234", "2001:db8:aa::/56"},
+		{IPRatePolicy{PrefixOverrides: []PrefixOverride{{carrier, 56}, {shared, 128}}}, "[2001:db8:aa:bb::1]:1234", "2001:db8:aa:bb::1"},
+		{IPRatePolicy{PrefixOverrides: []PrefixOverride{{office, 24}}}, "192.0.2.99:1234", "192.0.2.0/24"},
+		{IPRatePolicy{PrefixOverrides: []PrefixOverride{{office, 24}}}, "198.51.100.1:1234", "198.51.100.1"},
+	} {
+		client, err := c.policy.GetClient(newProxiedRequest(c.peer, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if client != c.expected {
+			t.Fatalf("(%d) Wrong client %s expected: %s", i, client, c.expected)
+		}
+	}
+}
+
+func TestIPv6RotationRateLimit(t *testing.T) {
+	h := &hitCountHandler{}
+	r := NewRateLimiter(2, time.Hour, IPRatePolicy{IPv6Prefix: 64}, h)
+
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newProxiedRequest(fmt.Sprintf("[2001:db8::%x]:1234", i+1), nil))
+	}
+
+	if h.hitCount != 2 {
+		t.Fatal("Rotating addresses in a network should share the limit:", h.hitCount)
+	}
+}
@@ -0,0 +1,131 @@
+package walgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// The maximum number of body bytes kept in a DecodeError.
+const decodeErrorBodySize = 512
+
+// DecodeError is returned by the JSON helpers when the response body can't
+// be decoded into the requested type.
+type DecodeError struct {
+	// Code is the status code of the response.
+	Code int
+
+	// Body holds the first bytes of the response body.
+	Body []byte
+
+	// Err is the error from the JSON decoder.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding response (%d): %s: %s", e.Code, e.Err, string(e.Body))
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// JSONError is returned by the JSON helpers for non-2xx responses when the
+// request is made with the DecodeErrors option. It holds the decoded error
+// body alongside the StatusError.
+type JSONError[E any] struct {
+	*StatusError
+
+	// Value is the decoded response body.
+	Value E
+}
+
+func (e *JSONError[E]) Unwrap() error {
+	return e.StatusError
+}
+
+// DecodeErrors makes the JSON helpers decode the body of non-2xx responses
+// into E and return it as a *JSONError[E]. If the body can't be decoded
+// the plain *StatusError is returned. As a default option of a requester
+// it applies to all of its JSON requests.
+func DecodeErrors[E any]() RequestOption {
+	return func(o *requestOptions) {
+		o.decodeError = func(statusErr *StatusError) error {
+			var value E
+			if err := json.Unmarshal(statusErr.Response.Data(), &value); err != nil {
+				return statusErr
+			}
+			return &JSONError[E]{StatusError: statusErr, Value: value}
+		}
+	}
+}
+
+// GetJSON performs a GET request using the given Requester and decodes the
+// response body as JSON into a T. If the Requester is nil the default
+// requester is used. Non-2xx responses are returned as a *StatusError (or
+// a *JSONError when DecodeErrors is given).
+func GetJSON[T any](ctx context.Context, r Requester, url string, p ParameterMap, opts ...RequestOption) (v T, err error) {
+	return doJSON[T](ctx, r, http.MethodGet, url, p, nil, opts)
+}
+
+// PostJSON performs a POST request using the given Requester with the body
+// encoded as JSON and decodes the response body as JSON into a Resp. It
+// otherwise works like GetJSON.
+func PostJSON[Req, Resp any](ctx context.Context, r Requester, url string, p ParameterMap, body Req, opts ...RequestOption) (v Resp, err error) {
+	return doJSON[Resp](ctx, r, http.MethodPost, url, p, JsonBody(body), opts)
+}
+
+// PutJSON performs a PUT request using the given Requester with the body
+// encoded as JSON and decodes the response body as JSON into a Resp. It
+// otherwise works like GetJSON.
+func PutJSON[Req, Resp any](ctx context.Context, r Requester, url string, p ParameterMap, body Req, opts ...RequestOption) (v Resp, err error) {
+	return doJSON[Resp](ctx, r, http.MethodPut, url, p, JsonBody(body), opts)
+}
+
+// PatchJSON performs a PATCH request using the given Requester with the
+// body encoded as JSON and decodes the response body as JSON into a Resp.
+// It otherwise works like GetJSON.
+func PatchJSON[Req, Resp any](ctx context.Context, r Requester, url string, p ParameterMap, body Req, opts ...RequestOption) (v Resp, err error) {
+	return doJSON[Resp](ctx, r, http.MethodPatch, url, p, JsonBody(body), opts)
+}
+
+func doJSON[T any](ctx context.Context, r Requester, method, url string, p ParameterMap, body Body, opts []RequestOption) (v T, err error) {
+	if r == nil {
+		r = defaultRequester
+	}
+
+	opts = append([]RequestOption{WithHeader("Accept", jsonContentType)}, opts...)
+	opts = append(opts, StatusErrors())
+
+	res, err := r.DoContext(ctx, method, url, p, body, opts...)
+	if err != nil {
+		// The decoder is taken from the error as DecodeErrors may also be
+		// one of the defaults of the requester.
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.decode != nil {
+			return v, statusErr.decode(statusErr)
+		}
+		return v, err
+	}
+
+	// Streamed responses (see Stream) only have a body to read from.
+	resBody := res.Body()
+	defer resBody.Close()
+
+	data, err := ioutil.ReadAll(resBody)
+	if err != nil || len(data) == 0 {
+		return v, err
+	}
+
+	if err = json.Unmarshal(data, &v); err != nil {
+		if len(data) > decodeErrorBodySize {
+			data = data[:decodeErrorBodySize]
+		}
+		return v, &DecodeError{Code: res.Code(), Body: data, Err: err}
+	}
+
+	return v, nil
+}
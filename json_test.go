@@ -0,0 +1,89 @@
+package walgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type testUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type testProblem struct {
+	Title string `json:"title"`
+}
+
+func TestGetJSON(t *testing.T) {
+	server := newJSONServer()
+	defer server.Close()
+
+	u, err := GetJSON[testUser](context.Background(), nil, server.URL+"/user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Name != "Alice" || u.Age != 30 {
+		t.Fatal("Unexpected user:", u)
+	}
+}
+
+func TestPostJSON(t *testing.T) {
+	server := newJSONServer()
+	defer server.Close()
+
+	u, err := PostJSON[testUser, testUser](context.Background(), defaultRequester, server.URL+"/user", nil, testUser{"Bob", 41})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Name != "Bob" || u.Age != 42 {
+		t.Fatal("Unexpected user:", u)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	server := newJSONServer()
+	defer server.Close()
+
+	_, err := GetJSON[testUser](context.Background(), nil, server.URL+"/missing", nil)
+	if !IsNotFound(err) {
+		t.Fatal("Expected not found:", err)
+	}
+
+	_, err = GetJSON[testUser](context.Background(), nil, server.URL+"/missing", nil, DecodeErrors[testProblem]())
+	var jsonErr *JSONError[testProblem]
+	if !errors.As(err, &jsonErr) || jsonErr.Value.Title != "No such user" {
+		t.Fatal("Expected decoded error body:", err)
+	}
+
+	if !IsNotFound(err) {
+		t.Fatal("JSON error should unwrap to the status error.")
+	}
+
+	_, err = GetJSON[testUser](context.Background(), nil, server.URL+"/broken", nil)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || string(decodeErr.Body) != "<html>" {
+		t.Fatal("Expected decode error with body:", err)
+	}
+}
+
+func TestJSONRequesterDefaults(t *testing.T) {
+	server := newJSONServer()
+	defer server.Close()
+
+	r := NewRequester(http.DefaultClient, "", "", DecodeErrors[testProblem](), Stream())
+
+	u, err := GetJSON[testUser](context.Background(), r, server.URL+"/user", nil)
+	if err != nil || u.Name != "Alice" || u.Age != 30 {
+		t.Fatal("Streamed response should be decoded:", u, err)
+	}
+
+	_, err = GetJSON[testUser](context.Background(), r, server.URL+"/missing", nil)
+	var jsonErr *JSONError[testProblem]
+	if !errors.As(err, &jsonErr) || jsonErr.Value.Title != "No such user" {
+		t.Fatal("DecodeErrors of the requester should be used:", err)
+	}
+}
@@ -0,0 +1,409 @@
+package walgo
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimitAlgorithm creates the Limiter keeping track of the requests of a
+// single client (or key). It is used by the RateLimitHandler and the
+// RateLimitRequester.
+type RateLimitAlgorithm interface {
+	NewLimiter() Limiter
+}
+
+// Limiter decides if requests are allowed by the rules of an algorithm.
+// Limiters are only used while holding the lock of the handler or requester
+// owning them, so they don't need to be safe for concurrent use.
+type Limiter interface {
+	// Allow checks if a request made at the given time is allowed and, if
+	// so, takes it from the budget.
+	Allow(now time.Time) RateLimitResult
+
+	// Idle reports whether the limiter is in the same state as a new one,
+	// so it can be dropped without changing the outcome of later requests.
+	Idle(now time.Time) bool
+}
+
+// RateLimitResult is the outcome of a call to Limiter.Allow.
+type RateLimitResult struct {
+	Allowed bool
+
+	// Limit is the number of requests allowed in a window - or the burst
+	// size for algorithms without a window.
+	Limit int
+
+	// Window is the period Limit applies to. For algorithms without a
+	// window it is the time it takes to refill a full burst.
+	Window time.Duration
+
+	// Remaining is the number of requests that are allowed right away after
+	// this one.
+	Remaining int
+
+	// Reset is how long until the full limit is available again.
+	Reset time.Duration
+
+	// RetryAfter is how long until a request is allowed if this one wasn't.
+	RetryAfter time.Duration
+}
+
+// SlidingLog allows Limit requests in any period of Duration by keeping the
+// time of every request in the period. This is the algorithm used by
+// NewRateLimiter and NewRateLimitRequester.
+type SlidingLog struct {
+	Limit    int
+	Duration time.Duration
+}
+
+// TokenBucket refills Limit tokens every Duration into a bucket holding at
+// most Burst tokens. Each request takes a token. If Burst is 0, Limit is
+// used.
+type TokenBucket struct {
+	Limit    int
+	Duration time.Duration
+	Burst    int
+}
+
+// GCRA is the generic cell rate algorithm. It allows one request every
+// Duration/Limit with bursts of up to Burst requests. If Burst is 0, Limit
+// is used. It behaves like TokenBucket but only stores a single timestamp.
+type GCRA struct {
+	Limit    int
+	Duration time.Duration
+	Burst    int
+}
+
+// FixedWindow allows Limit requests in each period of Duration, counted
+// from the zero time.
+type FixedWindow struct {
+	Limit    int
+	Duration time.Duration
+}
+
+// SlidingWindowCounter allows approximately Limit requests in any period of
+// Duration. It counts the requests of the current and previous fixed
+// window and weighs the previous count by how much of it overlaps the
+// sliding window.
+type SlidingWindowCounter struct {
+	Limit    int
+	Duration time.Duration
+}
+
+// NewLimiter creates a sliding log limiter.
+func (a SlidingLog) NewLimiter() Limiter {
+	return &slidingLog{limit: a.Limit, duration: a.Duration}
+}
+
+// NewLimiter creates a token bucket limiter with a full bucket.
+func (a TokenBucket) NewLimiter() Limiter {
+	burst := a.Burst
+	if burst == 0 {
+		burst = a.Limit
+	}
+	return &tokenBucket{limit: a.Limit, duration: a.Duration, burst: burst, tokens: float64(burst)}
+}
+
+// NewLimiter creates a GCRA limiter.
+func (a GCRA) NewLimiter() Limiter {
+	burst := a.Burst
+	if burst == 0 {
+		burst = a.Limit
+	}
+	return &gcra{limit: a.Limit, duration: a.Duration, burst: burst}
+}
+
+// NewLimiter creates a fixed window limiter.
+func (a FixedWindow) NewLimiter() Limiter {
+	return &fixedWindow{limit: a.Limit, duration: a.Duration}
+}
+
+// NewLimiter creates a sliding window counter limiter.
+func (a SlidingWindowCounter) NewLimiter() Limiter {
+	return &slidingWindowCounter{limit: a.Limit, duration: a.Duration}
+}
+
+type slidingLog struct {
+	limit    int
+	duration time.Duration
+	requests []int64
+}
+
+// Removes the requests that have left the window.
+func (l *slidingLog) prune(now time.Time) {
+	start := now.UnixNano() - int64(l.duration)
+
+	i := 0
+	for i < len(l.requests) && l.requests[i] <= start {
+		i++
+	}
+	l.requests = l.requests[i:]
+}
+
+func (l *slidingLog) Allow(now time.Time) (res RateLimitResult) {
+	l.prune(now)
+	res.Limit = l.limit
+	res.Window = l.duration
+
+	if len(l.requests) < l.limit {
+		l.requests = append(l.requests, now.UnixNano())
+		res.Allowed = true
+	} else if l.limit > 0 {
+		oldest := l.requests[len(l.requests)-l.limit]
+		res.RetryAfter = time.Duration(oldest + int64(l.duration) - now.UnixNano())
+	} else {
+		res.RetryAfter = l.duration
+	}
+
+	res.Remaining = l.limit - len(l.requests)
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	if len(l.requests) > 0 {
+		res.Reset = time.Duration(l.requests[len(l.requests)-1] + int64(l.duration) - now.UnixNano())
+	}
+	return res
+}
+
+func (l *slidingLog) Idle(now time.Time) bool {
+	l.prune(now)
+	return len(l.requests) == 0
+}
+
+type tokenBucket struct {
+	limit    int
+	duration time.Duration
+	burst    int
+	tokens   float64
+	last     time.Time
+}
+
+// Adds the tokens for the time passed since the last refill.
+func (l *tokenBucket) refill(now time.Time) {
+	if l.limit > 0 && l.duration > 0 && now.After(l.last) {
+		added := float64(now.Sub(l.last)) * float64(l.limit) / float64(l.duration)
+		l.tokens = math.Min(float64(l.burst), l.tokens+added)
+	}
+	l.last = now
+}
+
+// Returns how long it takes for the bucket to hold the given number of
+// tokens.
+func (l *tokenBucket) until(tokens float64) time.Duration {
+	if l.tokens >= tokens {
+		return 0
+	}
+	if l.limit <= 0 || l.duration <= 0 {
+		return l.duration
+	}
+	return time.Duration(math.Ceil((tokens - l.tokens) * float64(l.duration) / float64(l.limit)))
+}
+
+func (l *tokenBucket) Allow(now time.Time) (res RateLimitResult) {
+	l.refill(now)
+	res.Limit = l.burst
+	if l.limit > 0 {
+		res.Window = l.duration * time.Duration(l.burst) / time.Duration(l.limit)
+	}
+
+	if l.tokens >= 1 {
+		l.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.until(1)
+	}
+
+	res.Remaining = int(l.tokens)
+	res.Reset = l.until(float64(l.burst))
+	return res
+}
+
+func (l *tokenBucket) Idle(now time.Time) bool {
+	l.refill(now)
+	return l.tokens >= float64(l.burst)
+}
+
+type gcra struct {
+	limit    int
+	duration time.Duration
+	burst    int
+
+	// The theoretical arrival time of the next request.
+	tat time.Time
+}
+
+func (l *gcra) Allow(now time.Time) (res RateLimitResult) {
+	res.Limit = l.burst
+
+	if l.limit <= 0 || l.burst <= 0 {
+		res.RetryAfter = l.duration
+		return res
+	}
+	res.Window = l.duration * time.Duration(l.burst) / time.Duration(l.limit)
+
+	interval := l.duration / time.Duration(l.limit)
+	tolerance := interval * time.Duration(l.burst)
+
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(interval)
+	if allowAt := next.Add(-tolerance); now.Before(allowAt) {
+		res.RetryAfter = allowAt.Sub(now)
+		res.Reset = tat.Sub(now)
+		return res
+	}
+
+	l.tat = next
+	res.Allowed = true
+	res.Reset = next.Sub(now)
+	if interval > 0 {
+		res.Remaining = int((tolerance - res.Reset) / interval)
+	} else {
+		res.Remaining = l.burst
+	}
+	return res
+}
+
+func (l *gcra) Idle(now time.Time) bool {
+	return !l.tat.After(now)
+}
+
+type fixedWindow struct {
+	limit    int
+	duration time.Duration
+	start    time.Time
+	count    int
+}
+
+// Starts a new window if the current one has ended.
+func (l *fixedWindow) advance(now time.Time) {
+	if now.Before(l.start.Add(l.duration)) {
+		return
+	}
+
+	if l.duration > 0 {
+		l.start = now.Truncate(l.duration)
+	} else {
+		l.start = now
+	}
+	l.count = 0
+}
+
+func (l *fixedWindow) Allow(now time.Time) (res RateLimitResult) {
+	l.advance(now)
+	res.Limit = l.limit
+	res.Window = l.duration
+	res.Reset = l.start.Add(l.duration).Sub(now)
+
+	if l.count < l.limit {
+		l.count++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = res.Reset
+	}
+
+	res.Remaining = l.limit - l.count
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res
+}
+
+func (l *fixedWindow) Idle(now time.Time) bool {
+	l.advance(now)
+	return l.count == 0
+}
+
+type slidingWindowCounter struct {
+	limit    int
+	duration time.Duration
+	start    time.Time
+	current  int
+	previous int
+}
+
+// Moves the current window forward to the one holding the given time.
+func (l *slidingWindowCounter) advance(now time.Time) {
+	if l.duration <= 0 {
+		l.start, l.current, l.previous = now, 0, 0
+		return
+	}
+
+	if now.Before(l.start.Add(l.duration)) {
+		return
+	}
+
+	if now.Before(l.start.Add(2 * l.duration)) {
+		l.previous = l.current
+	} else {
+		l.previous = 0
+	}
+	l.start = now.Truncate(l.duration)
+	l.current = 0
+}
+
+// Returns the weighted number of requests in the sliding window.
+func (l *slidingWindowCounter) estimate(now time.Time) float64 {
+	if l.duration <= 0 {
+		return float64(l.current)
+	}
+	weight := 1 - float64(now.Sub(l.start))/float64(l.duration)
+	return float64(l.previous)*weight + float64(l.current)
+}
+
+// Returns how long until the estimate leaves room for another request.
+func (l *slidingWindowCounter) retryAfter(now time.Time) time.Duration {
+	if l.limit <= 0 {
+		return l.duration
+	}
+
+	elapsed := float64(now.Sub(l.start))
+	d := float64(l.duration)
+	free := float64(l.limit - 1)
+
+	// Room frees up in the current window as the previous window slides
+	// out of it.
+	if l.previous > 0 && l.current <= l.limit-1 {
+		at := d * (1 - (free-float64(l.current))/float64(l.previous))
+		return time.Duration(math.Ceil(at - elapsed))
+	}
+
+	// Otherwise the current count has to slide out in the next window.
+	at := d * (1 - free/float64(l.current))
+	return time.Duration(math.Ceil(d - elapsed + at))
+}
+
+func (l *slidingWindowCounter) Allow(now time.Time) (res RateLimitResult) {
+	l.advance(now)
+	res.Limit = l.limit
+	res.Window = l.duration
+
+	estimate := l.estimate(now)
+	if estimate+1 <= float64(l.limit) {
+		l.current++
+		estimate++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.retryAfter(now)
+	}
+
+	res.Remaining = int(float64(l.limit) - estimate)
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+
+	if l.current > 0 {
+		res.Reset = l.start.Add(2 * l.duration).Sub(now)
+	} else if l.previous > 0 {
+		res.Reset = l.start.Add(l.duration).Sub(now)
+	}
+	return res
+}
+
+func (l *slidingWindowCounter) Idle(now time.Time) bool {
+	l.advance(now)
+	return l.current == 0 && l.previous == 0
+}
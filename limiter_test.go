@@ -0,0 +1,163 @@
+package walgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type limiterStep struct {
+	offset     time.Duration
+	allowed    bool
+	retryAfter time.Duration
+}
+
+func testLimiter(t *testing.T, name string, l Limiter, steps []limiterStep) {
+	start := time.Unix(1700000000, 0)
+
+	for i, s := range steps {
+		res := l.Allow(start.Add(s.offset))
+		if res.Allowed != s.allowed {
+			t.Fatalf("%s (%d): allowed should be %v: %+v", name, i, s.allowed, res)
+		}
+		if !s.allowed && res.RetryAfter != s.retryAfter {
+			t.Fatalf("%s (%d): retry after should be %v: %+v", name, i, s.retryAfter, res)
+		}
+	}
+}
+
+func TestSlidingLog(t *testing.T) {
+	testLimiter(t, "sliding log", SlidingLog{Limit: 2, Duration: time.Second}.NewLimiter(), []limiterStep{
+		{0, true, 0},
+		{100 * time.Millisecond, true, 0},
+		{200 * time.Millisecond, false, 800 * time.Millisecond},
+		{time.Second, true, 0},
+		{time.Second, false, 100 * time.Millisecond},
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	l := TokenBucket{Limit: 1, Duration: time.Second, Burst: 3}.NewLimiter()
+	testLimiter(t, "token bucket", l, []limiterStep{
+		{0, true, 0},
+		{0, true, 0},
+		{0, true, 0},
+		{0, false, time.Second},
+		{500 * time.Millisecond, false, 500 * time.Millisecond},
+		{time.Second, true, 0},
+		{time.Second, false, time.Second},
+	})
+
+	if l.Idle(time.Unix(1700000000, 0).Add(3 * time.Second)) {
+		t.Fatal("Bucket should not be full yet.")
+	}
+	if !l.Idle(time.Unix(1700000000, 0).Add(4 * time.Second)) {
+		t.Fatal("Bucket should be full.")
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	testLimiter(t, "gcra", GCRA{Limit: 1, Duration: time.Second, Burst: 2}.NewLimiter(), []limiterStep{
+		{0, true, 0},
+		{0, true, 0},
+		{0, false, time.Second},
+		{time.Second, true, 0},
+		{time.Second, false, time.Second},
+		{5 * time.Second, true, 0},
+		{5 * time.Second, true, 0},
+		{5 * time.Second, false, time.Second},
+	})
+}
+
+func TestFixedWindow(t *testing.T) {
+	testLimiter(t, "fixed window", FixedWindow{Limit: 2, Duration: time.Second}.NewLimiter(), []limiterStep{
+		{0, true, 0},
+		{0, true, 0},
+		{0, false, time.Second},
+		{500 * time.Millisecond, false, 500 * time.Millisecond},
+		{time.Second, true, 0},
+		{time.Second, true, 0},
+	})
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	testLimiter(t, "sliding window counter", SlidingWindowCounter{Limit: 2, Duration: time.Second}.NewLimiter(), []limiterStep{
+		{0, true, 0},
+		{0, true, 0},
+		{500 * time.Millisecond, false, time.Second},
+		{time.Second, false, 500 * time.Millisecond},
+		{1500 * time.Millisecond, true, 0},
+		{1500 * time.Millisecond, false, 500 * time.Millisecond},
+	})
+}
+
+func TestZeroLimitAlgorithms(t *testing.T) {
+	for _, a := range []RateLimitAlgorithm{
+		SlidingLog{Duration: time.Second},
+		TokenBucket{Duration: time.Second},
+		GCRA{Duration: time.Second},
+		FixedWindow{Duration: time.Second},
+		SlidingWindowCounter{Duration: time.Second},
+	} {
+		if res := a.NewLimiter().Allow(time.Now()); res.Allowed {
+			t.Fatalf("%T with a zero limit should not allow requests.", a)
+		}
+	}
+}
+
+func TestTokenBucketRateLimiter(t *testing.T) {
+	h := &hitCountHandler{}
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm: TokenBucket{Limit: 1, Duration: time.Hour, Burst: 5},
+	}, IPRatePolicy{}, h)
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if i < 5 && w.Code != http.StatusOK {
+			t.Fatalf("(%d) Wrong code (%d) expected: %d", i, w.Code, http.StatusOK)
+		}
+		if i >= 5 && w.Code != http.StatusTooManyRequests {
+			t.Fatalf("(%d) Wrong code (%d) expected: %d", i, w.Code, http.StatusTooManyRequests)
+		}
+	}
+
+	if h.hitCount != 5 {
+		t.Fatal("Wrong hit count:", h.hitCount)
+	}
+}
+
+func TestRateLimiterConfigDefaultAlgorithm(t *testing.T) {
+	for _, config := range []RateLimiterConfig{{}, {Limit: 2, Duration: time.Hour}} {
+		h := &hitCountHandler{}
+		r := NewRateLimiterWithConfig(config, IPRatePolicy{}, h)
+
+		for i := 0; i < 3; i++ {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com", nil))
+		}
+
+		if h.hitCount != config.Limit {
+			t.Fatal("Wrong hit count:", h.hitCount, "expected:", config.Limit)
+		}
+	}
+}
+
+func TestAlgorithmRateLimitRequester(t *testing.T) {
+	server := newMethodServer()
+	defer server.Close()
+
+	requester := NewAlgorithmRateLimitRequester(defaultRequester, GCRA{Limit: 1, Duration: time.Hour, Burst: 2})
+
+	for i := 0; i < 3; i++ {
+		_, err := requester.Get(server.URL, nil)
+		if i < 2 && err != nil {
+			t.Fatal(err)
+		}
+		if i >= 2 && err != RateLimitExceededErr {
+			t.Fatal("Allowed to request beyond rate limit:", i, err)
+		}
+	}
+}
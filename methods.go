@@ -1,6 +1,8 @@
 package walgo
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -20,129 +22,485 @@ func (p ParameterMap) AddInt(key string, value int) {
 }
 
 // Get performs the Get function on the default requester.
-func Get(url string, p ParameterMap) (res Response, err error) {
-	return defaultRequester.Get(url, p)
+func Get(url string, p ParameterMap, opts ...RequestOption) (res Response, err error) {
+	return defaultRequester.Get(url, p, opts...)
+}
+
+// GetContext performs the GetContext function on the default requester.
+func GetContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (res Response, err error) {
+	return defaultRequester.GetContext(ctx, url, p, opts...)
 }
 
 // Post performs the Post functions on the default requester.
-func Post(url string, p ParameterMap) (r Response, err error) {
-	return defaultRequester.Post(url, p)
+func Post(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.Post(url, p, opts...)
+}
+
+// PostContext performs the PostContext function on the default requester.
+func PostContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostContext(ctx, url, p, opts...)
 }
 
 // PostRaw performs the PostRaw function on the default requester.
-func PostRaw(url string, p ParameterMap, data []byte) (r Response, err error) {
-	return defaultRequester.PostRaw(url, p, data)
+func PostRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostRaw(url, p, data, opts...)
+}
+
+// PostRawContext performs the PostRawContext function on the default
+// requester.
+func PostRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostRawContext(ctx, url, p, data, opts...)
 }
 
 // PostMultipart performs the PostMultipart function on the default requester.
-func PostMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error) {
-	return defaultRequester.PostMultipart(url, p, m)
+func PostMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostMultipart(url, p, m, opts...)
+}
+
+// PostMultipartContext performs the PostMultipartContext function on the
+// default requester.
+func PostMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostMultipartContext(ctx, url, p, m, opts...)
 }
 
 // PostValues performs the PostValues function on the default requester.
-func PostValues(url string, p ParameterMap, v url.Values) (r Response, err error) {
-	return defaultRequester.PostValues(url, p, v)
+func PostValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostValues(url, p, v, opts...)
+}
+
+// PostValuesContext performs the PostValuesContext function on the default
+// requester.
+func PostValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostValuesContext(ctx, url, p, v, opts...)
+}
+
+// PostReader performs the PostReader function on the default requester.
+func PostReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostReader(url, p, body, length, opts...)
+}
+
+// PostReaderContext performs the PostReaderContext function on the default
+// requester.
+func PostReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostReaderContext(ctx, url, p, body, length, opts...)
 }
 
 // PostJson performs the PostJson function on the default requester.
-func PostJson(url string, p ParameterMap, v interface{}) (r Response, err error) {
-	return defaultRequester.PostJson(url, p, v)
+func PostJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostJson(url, p, v, opts...)
+}
+
+// PostJsonContext performs the PostJsonContext function on the default
+// requester.
+func PostJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PostJsonContext(ctx, url, p, v, opts...)
 }
 
 // Put performs the Put function on the default requester.
-func Put(url string, p ParameterMap) (r Response, err error) {
-	return defaultRequester.Put(url, p)
+func Put(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.Put(url, p, opts...)
+}
+
+// PutContext performs the PutContext function on the default requester.
+func PutContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutContext(ctx, url, p, opts...)
 }
 
 // PutRaw performs the PutRaw function on the default requester.
-func PutRaw(url string, p ParameterMap, data []byte) (r Response, err error) {
-	return defaultRequester.PutRaw(url, p, data)
+func PutRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutRaw(url, p, data, opts...)
+}
+
+// PutRawContext performs the PutRawContext function on the default
+// requester.
+func PutRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutRawContext(ctx, url, p, data, opts...)
 }
 
 // PutMultipart performs the PutMultipart function on the default requester.
-func PutMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error) {
-	return defaultRequester.PutMultipart(url, p, m)
+func PutMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutMultipart(url, p, m, opts...)
+}
+
+// PutMultipartContext performs the PutMultipartContext function on the
+// default requester.
+func PutMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutMultipartContext(ctx, url, p, m, opts...)
 }
 
 // PutValues performs the PutValues function on the default requester.
-func PutValues(url string, p ParameterMap, v url.Values) (r Response, err error) {
-	return defaultRequester.PutValues(url, p, v)
+func PutValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutValues(url, p, v, opts...)
+}
+
+// PutValuesContext performs the PutValuesContext function on the default
+// requester.
+func PutValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutValuesContext(ctx, url, p, v, opts...)
+}
+
+// PutReader performs the PutReader function on the default requester.
+func PutReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutReader(url, p, body, length, opts...)
+}
+
+// PutReaderContext performs the PutReaderContext function on the default
+// requester.
+func PutReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutReaderContext(ctx, url, p, body, length, opts...)
 }
 
 // PutJson performs the PutJson function on the default requster.
-func PutJson(url string, p ParameterMap, v interface{}) (r Response, err error) {
-	return defaultRequester.PutJson(url, p, v)
+func PutJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutJson(url, p, v, opts...)
+}
+
+// PutJsonContext performs the PutJsonContext function on the default
+// requester.
+func PutJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PutJsonContext(ctx, url, p, v, opts...)
 }
 
 // Delete performs the Delete function on the default requster.
-func Delete(url string, p ParameterMap) (r Response, err error) {
-	return defaultRequester.Delete(url, p)
+func Delete(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.Delete(url, p, opts...)
+}
+
+// DeleteContext performs the DeleteContext function on the default
+// requester.
+func DeleteContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.DeleteContext(ctx, url, p, opts...)
+}
+
+// Patch performs the Patch function on the default requester.
+func Patch(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.Patch(url, p, opts...)
+}
+
+// PatchContext performs the PatchContext function on the default requester.
+func PatchContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchContext(ctx, url, p, opts...)
+}
+
+// PatchJson performs the PatchJson function on the default requester.
+func PatchJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchJson(url, p, v, opts...)
+}
+
+// PatchJsonContext performs the PatchJsonContext function on the default
+// requester.
+func PatchJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchJsonContext(ctx, url, p, v, opts...)
+}
+
+// PatchRaw performs the PatchRaw function on the default requester.
+func PatchRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchRaw(url, p, data, opts...)
+}
+
+// PatchRawContext performs the PatchRawContext function on the default
+// requester.
+func PatchRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchRawContext(ctx, url, p, data, opts...)
+}
+
+// PatchMultipart performs the PatchMultipart function on the default
+// requester.
+func PatchMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchMultipart(url, p, m, opts...)
+}
+
+// PatchMultipartContext performs the PatchMultipartContext function on the
+// default requester.
+func PatchMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchMultipartContext(ctx, url, p, m, opts...)
+}
+
+// PatchValues performs the PatchValues function on the default requester.
+func PatchValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchValues(url, p, v, opts...)
+}
+
+// PatchValuesContext performs the PatchValuesContext function on the default
+// requester.
+func PatchValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchValuesContext(ctx, url, p, v, opts...)
+}
+
+// PatchReader performs the PatchReader function on the default requester.
+func PatchReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchReader(url, p, body, length, opts...)
+}
+
+// PatchReaderContext performs the PatchReaderContext function on the default
+// requester.
+func PatchReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.PatchReaderContext(ctx, url, p, body, length, opts...)
+}
+
+// Head performs the Head function on the default requester.
+func Head(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.Head(url, p, opts...)
+}
+
+// HeadContext performs the HeadContext function on the default requester.
+func HeadContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.HeadContext(ctx, url, p, opts...)
+}
+
+// Options performs the Options function on the default requester.
+func Options(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.Options(url, p, opts...)
+}
+
+// OptionsContext performs the OptionsContext function on the default
+// requester.
+func OptionsContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.OptionsContext(ctx, url, p, opts...)
+}
+
+// Do performs the Do function on the default requester.
+func Do(method, url string, p ParameterMap, body Body, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.Do(method, url, p, body, opts...)
+}
+
+// DoContext performs the DoContext function on the default requester.
+func DoContext(ctx context.Context, method, url string, p ParameterMap, body Body, opts ...RequestOption) (r Response, err error) {
+	return defaultRequester.DoContext(ctx, method, url, p, body, opts...)
+}
+
+// requestMethods implements the request methods of the Requester interface
+// by building the payload and handing it to send. Requesters that operate
+// on the built payload embed it and set send to their makeRequest.
+type requestMethods struct {
+	send func(ctx context.Context, url string, p ParameterMap, method string, l *payload, opts ...RequestOption) (r Response, err error)
+}
+
+// Sends a streamed payload and releases it if it wasn't sent, e.g. because
+// the request failed before it reached the HTTP client.
+func (f *requestMethods) sendStreamed(ctx context.Context, url string, p ParameterMap, method string, l *payload, opts ...RequestOption) (r Response, err error) {
+	defer l.release()
+	return f.send(ctx, url, p, method, l, opts...)
+}
+
+func (f *requestMethods) Get(url string, p ParameterMap, opts ...RequestOption) (res Response, err error) {
+	return f.GetContext(context.Background(), url, p, opts...)
+}
+
+func (f *requestMethods) GetContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (res Response, err error) {
+	return f.send(ctx, url, p, http.MethodGet, nil, opts...)
 }
 
-func (f *requesterImpl) Get(url string, p ParameterMap) (res Response, err error) {
-	return f.makeRequest(url, p, http.MethodGet, nil)
+func (f *requestMethods) Post(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.PostContext(context.Background(), url, p, opts...)
 }
 
-func (f *requesterImpl) Post(url string, p ParameterMap) (r Response, err error) {
-	return f.makeRequest(url, p, http.MethodPost, nil)
+func (f *requestMethods) PostContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPost, nil, opts...)
 }
 
-func (f *requesterImpl) PostRaw(url string, p ParameterMap, data []byte) (r Response, err error) {
-	return f.makeRequest(url, p, http.MethodPost, payloadFromRawData(data))
+func (f *requestMethods) PostRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return f.PostRawContext(context.Background(), url, p, data, opts...)
 }
 
-func (f *requesterImpl) PostMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error) {
+func (f *requestMethods) PostRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPost, payloadFromRawData(data), opts...)
+}
+
+func (f *requestMethods) PostMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return f.PostMultipartContext(context.Background(), url, p, m, opts...)
+}
+
+func (f *requestMethods) PostMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
 	payload, err := payloadFromMultipart(m)
 	if err != nil {
 		return nil, err
 	}
 
-	return f.makeRequest(url, p, http.MethodPost, payload)
+	return f.sendStreamed(ctx, url, p, http.MethodPost, payload, opts...)
+}
+
+func (f *requestMethods) PostValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return f.PostValuesContext(context.Background(), url, p, v, opts...)
+}
+
+func (f *requestMethods) PostValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPost, payloadFromValues(v), opts...)
+}
+
+func (f *requestMethods) PostReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return f.PostReaderContext(context.Background(), url, p, body, length, opts...)
 }
 
-func (f *requesterImpl) PostValues(url string, p ParameterMap, v url.Values) (r Response, err error) {
-	return f.makeRequest(url, p, http.MethodPost, payloadFromValues(v))
+func (f *requestMethods) PostReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return f.sendStreamed(ctx, url, p, http.MethodPost, payloadFromReader(body, length), opts...)
 }
 
-func (f *requesterImpl) PostJson(url string, p ParameterMap, v interface{}) (r Response, err error) {
+func (f *requestMethods) PostJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return f.PostJsonContext(context.Background(), url, p, v, opts...)
+}
+
+func (f *requestMethods) PostJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
 	payload, err := createJsonPayload(v)
 	if err != nil {
 		return nil, err
 	}
 
-	return f.makeRequest(url, p, http.MethodPost, payload)
+	return f.send(ctx, url, p, http.MethodPost, payload, opts...)
 }
 
-func (f *requesterImpl) Put(url string, p ParameterMap) (r Response, err error) {
-	return f.makeRequest(url, p, http.MethodPut, nil)
+func (f *requestMethods) Put(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.PutContext(context.Background(), url, p, opts...)
 }
 
-func (f *requesterImpl) PutRaw(url string, p ParameterMap, data []byte) (r Response, err error) {
-	return f.makeRequest(url, p, http.MethodPut, payloadFromRawData(data))
+func (f *requestMethods) PutContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPut, nil, opts...)
 }
 
-func (f *requesterImpl) PutMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error) {
+func (f *requestMethods) PutRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return f.PutRawContext(context.Background(), url, p, data, opts...)
+}
+
+func (f *requestMethods) PutRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPut, payloadFromRawData(data), opts...)
+}
+
+func (f *requestMethods) PutMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return f.PutMultipartContext(context.Background(), url, p, m, opts...)
+}
+
+func (f *requestMethods) PutMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
 	payload, err := payloadFromMultipart(m)
 	if err != nil {
 		return nil, err
 	}
 
-	return f.makeRequest(url, p, http.MethodPut, payload)
+	return f.sendStreamed(ctx, url, p, http.MethodPut, payload, opts...)
+}
+
+func (f *requestMethods) PutValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return f.PutValuesContext(context.Background(), url, p, v, opts...)
+}
+
+func (f *requestMethods) PutValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPut, payloadFromValues(v), opts...)
+}
+
+func (f *requestMethods) PutReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return f.PutReaderContext(context.Background(), url, p, body, length, opts...)
+}
+
+func (f *requestMethods) PutReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return f.sendStreamed(ctx, url, p, http.MethodPut, payloadFromReader(body, length), opts...)
+}
+
+func (f *requestMethods) PutJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return f.PutJsonContext(context.Background(), url, p, v, opts...)
+}
+
+func (f *requestMethods) PutJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	payload, err := createJsonPayload(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.send(ctx, url, p, http.MethodPut, payload, opts...)
+}
+
+func (f *requestMethods) Delete(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.DeleteContext(context.Background(), url, p, opts...)
+}
+
+func (f *requestMethods) DeleteContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodDelete, nil, opts...)
+}
+
+func (f *requestMethods) Patch(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.PatchContext(context.Background(), url, p, opts...)
+}
+
+func (f *requestMethods) PatchContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPatch, nil, opts...)
 }
 
-func (f *requesterImpl) PutValues(url string, p ParameterMap, v url.Values) (r Response, err error) {
-	return f.makeRequest(url, p, http.MethodPut, payloadFromValues(v))
+func (f *requestMethods) PatchJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
+	return f.PatchJsonContext(context.Background(), url, p, v, opts...)
 }
 
-func (f *requesterImpl) PutJson(url string, p ParameterMap, v interface{}) (r Response, err error) {
+func (f *requestMethods) PatchJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error) {
 	payload, err := createJsonPayload(v)
 	if err != nil {
 		return nil, err
 	}
 
-	return f.makeRequest(url, p, http.MethodPut, payload)
+	return f.send(ctx, url, p, http.MethodPatch, payload, opts...)
+}
+
+func (f *requestMethods) PatchRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return f.PatchRawContext(context.Background(), url, p, data, opts...)
+}
+
+func (f *requestMethods) PatchRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPatch, payloadFromRawData(data), opts...)
+}
+
+func (f *requestMethods) PatchMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	return f.PatchMultipartContext(context.Background(), url, p, m, opts...)
+}
+
+func (f *requestMethods) PatchMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error) {
+	payload, err := payloadFromMultipart(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.sendStreamed(ctx, url, p, http.MethodPatch, payload, opts...)
+}
+
+func (f *requestMethods) PatchValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return f.PatchValuesContext(context.Background(), url, p, v, opts...)
+}
+
+func (f *requestMethods) PatchValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodPatch, payloadFromValues(v), opts...)
+}
+
+func (f *requestMethods) PatchReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return f.PatchReaderContext(context.Background(), url, p, body, length, opts...)
 }
 
-func (f *requesterImpl) Delete(url string, p ParameterMap) (r Response, err error) {
-	return f.makeRequest(url, p, http.MethodDelete, nil)
+func (f *requestMethods) PatchReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error) {
+	return f.sendStreamed(ctx, url, p, http.MethodPatch, payloadFromReader(body, length), opts...)
+}
+
+func (f *requestMethods) Head(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.HeadContext(context.Background(), url, p, opts...)
+}
+
+func (f *requestMethods) HeadContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodHead, nil, opts...)
+}
+
+func (f *requestMethods) Options(url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.OptionsContext(context.Background(), url, p, opts...)
+}
+
+func (f *requestMethods) OptionsContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error) {
+	return f.send(ctx, url, p, http.MethodOptions, nil, opts...)
+}
+
+func (f *requestMethods) Do(method, url string, p ParameterMap, body Body, opts ...RequestOption) (r Response, err error) {
+	return f.DoContext(context.Background(), method, url, p, body, opts...)
+}
+
+func (f *requestMethods) DoContext(ctx context.Context, method, url string, p ParameterMap, body Body, opts ...RequestOption) (r Response, err error) {
+	var payload *payload
+	if body != nil {
+		payload, err = body.build()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f.sendStreamed(ctx, url, p, method, payload, opts...)
 }
@@ -1,10 +1,13 @@
 package walgo
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestGet(t *testing.T) {
@@ -178,3 +181,121 @@ func TestCustomRequester(t *testing.T) {
 		t.Fatal("Unexpected response code:", res.Code())
 	}
 }
+
+func TestGetContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	res, err := GetContext(context.Background(), server.URL, nil)
+	if err != nil || res.Error() != nil {
+		t.Fatal(err)
+	}
+
+	if res.String() != "ok" {
+		t.Fatal("Unexpected response body:", res.String())
+	}
+}
+
+func TestGetContextCancelled(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := GetContext(ctx, server.URL, nil)
+	if err == nil {
+		t.Fatal("Request should fail when the context times out.")
+	}
+
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatal("Context should have exceeded its deadline:", ctx.Err())
+	}
+}
+
+func TestPatch(t *testing.T) {
+	server := newMethodServer()
+	defer server.Close()
+
+	res, err := PatchJson(server.URL, nil, map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Header().Get("X-Method") != http.MethodPatch {
+		t.Fatal("Unexpected method:", res.Header().Get("X-Method"))
+	}
+
+	if res.String() != `{"foo":"bar"}` {
+		t.Fatal("Unexpected body:", res.String())
+	}
+}
+
+func TestHeadAndOptions(t *testing.T) {
+	server := newMethodServer()
+	defer server.Close()
+
+	res, err := Head(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Header().Get("X-Method") != http.MethodHead || len(res.Data()) != 0 {
+		t.Fatal("Unexpected HEAD response:", res.Header().Get("X-Method"), res.String())
+	}
+
+	res, err = Options(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Header().Get("X-Method") != http.MethodOptions {
+		t.Fatal("Unexpected method:", res.Header().Get("X-Method"))
+	}
+}
+
+func TestDo(t *testing.T) {
+	server := newMethodServer()
+	defer server.Close()
+
+	res, err := Do("PROPFIND", server.URL, nil, RawBody([]byte("data")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Header().Get("X-Method") != "PROPFIND" || res.String() != "data" {
+		t.Fatal("Unexpected response:", res.Header().Get("X-Method"), res.String())
+	}
+
+	res, err = Do(http.MethodGet, server.URL, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Header().Get("X-Method") != http.MethodGet || res.String() != "" {
+		t.Fatal("Unexpected response:", res.Header().Get("X-Method"), res.String())
+	}
+}
+
+func TestJsonContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Content-Type"))
+	}))
+	defer server.Close()
+
+	res, err := PostJson(server.URL, nil, map[string]int{"a": 1})
+	if err != nil || res.String() != "application/json" {
+		t.Fatal("JSON should be sent as application/json:", res, err)
+	}
+
+	res, err = PutJson(server.URL, nil, map[string]int{"a": 1}, WithHeader("Content-Type", "application/merge-patch+json"))
+	if err != nil || res.String() != "application/merge-patch+json" {
+		t.Fatal("Content-Type should be overridden:", res, err)
+	}
+}
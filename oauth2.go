@@ -0,0 +1,284 @@
+package walgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// NoAccessTokenErr is returned when the token endpoint responds without
+	// an access token.
+	NoAccessTokenErr = errors.New("No access token in token response.")
+)
+
+const (
+	// The default time before the expiry a token is refreshed.
+	defaultExpiryDelta = 10 * time.Second
+
+	// The default time limit of a token request.
+	defaultFetchTimeout = 30 * time.Second
+)
+
+// OAuth2Config holds the settings for fetching OAuth2 access tokens.
+type OAuth2Config struct {
+	// TokenURL is the URL of the token endpoint.
+	TokenURL string
+
+	// ClientID and ClientSecret are the client credentials. They are sent
+	// using HTTP Basic authentication unless AuthInBody is set.
+	ClientID     string
+	ClientSecret string
+	AuthInBody   bool
+
+	// Scopes are the scopes requested.
+	Scopes []string
+
+	// RefreshToken makes the token source use the refresh token grant
+	// instead of the client credentials grant. If the token endpoint
+	// returns a new refresh token it replaces this one.
+	RefreshToken string
+
+	// ExpiryDelta is how long before the expiry a token is refreshed. If it
+	// is 0, 10 seconds is used.
+	ExpiryDelta time.Duration
+
+	// FetchTimeout limits the time of a token request. The request is
+	// shared by all the callers waiting for a token so it isn't aborted
+	// when one of them gives up. If it is 0, 30 seconds is used.
+	FetchTimeout time.Duration
+
+	// Requester is used for the token requests. If it is nil the default
+	// requester is used. The token requests are sent without the
+	// authenticator of the requester, so it can be a requester
+	// authenticating with this token source.
+	Requester Requester
+}
+
+// OAuth2Token is an access token returned from the token endpoint.
+type OAuth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+
+	// Expiry is the time the token expires - or zero if it doesn't expire.
+	Expiry time.Time
+}
+
+// OAuth2ErrorBody is the error response from a token endpoint (RFC 6749
+// section 5.2). Failing token requests return a *JSONError[OAuth2ErrorBody]
+// if the endpoint responds with one.
+type OAuth2ErrorBody struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// OAuth2TokenSource fetches and caches access tokens using the client
+// credentials or refresh token grant. It is an Authenticator setting the
+// Authorization header with the current token - use it with
+// WithAuthenticator. It is safe to use concurrently and only one token
+// request is made at a time.
+type OAuth2TokenSource struct {
+	config OAuth2Config
+
+	lock         sync.Mutex
+	token        *OAuth2Token
+	refreshToken string
+	fetching     *tokenFetch
+}
+
+// Holds a token request in progress that other callers can wait for.
+type tokenFetch struct {
+	done  chan struct{}
+	token *OAuth2Token
+	err   error
+}
+
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// NewOAuth2TokenSource creates a new token source using the given config.
+func NewOAuth2TokenSource(c OAuth2Config) (s *OAuth2TokenSource) {
+	if c.ExpiryDelta == 0 {
+		c.ExpiryDelta = defaultExpiryDelta
+	}
+	if c.FetchTimeout == 0 {
+		c.FetchTimeout = defaultFetchTimeout
+	}
+
+	return &OAuth2TokenSource{
+		config:       c,
+		refreshToken: c.RefreshToken,
+	}
+}
+
+// Token returns the cached token if it is still valid. Otherwise a new
+// token is fetched - if a fetch is already in progress it is waited for.
+// Cancelling the context stops the waiting but not the fetch, which keeps
+// the values of the context of the caller starting it.
+func (s *OAuth2TokenSource) Token(ctx context.Context) (t OAuth2Token, err error) {
+	s.lock.Lock()
+	if s.valid() {
+		t = *s.token
+		s.lock.Unlock()
+		return t, nil
+	}
+
+	fetch := s.fetching
+	if fetch == nil {
+		fetch = &tokenFetch{done: make(chan struct{})}
+		s.fetching = fetch
+		go s.run(detachedContext{ctx}, fetch, s.refreshToken)
+	}
+	s.lock.Unlock()
+
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		return t, ctx.Err()
+	}
+
+	if fetch.err != nil {
+		return t, fetch.err
+	}
+
+	return *fetch.token, nil
+}
+
+// Fetches a token for the callers waiting for the fetch and caches it.
+func (s *OAuth2TokenSource) run(ctx context.Context, fetch *tokenFetch, refreshToken string) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.FetchTimeout)
+	defer cancel()
+
+	fetch.token, fetch.err = s.fetch(ctx, refreshToken)
+
+	s.lock.Lock()
+	if fetch.err == nil {
+		s.token = fetch.token
+		if fetch.token.RefreshToken != "" {
+			s.refreshToken = fetch.token.RefreshToken
+		}
+	}
+	s.fetching = nil
+	s.lock.Unlock()
+	close(fetch.done)
+}
+
+// A context with the values of its parent but without its deadline and
+// cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Invalidate discards the cached token so the next call to Token fetches
+// a new one.
+func (s *OAuth2TokenSource) Invalidate() {
+	s.lock.Lock()
+	s.token = nil
+	s.lock.Unlock()
+}
+
+// Authenticate sets the Authorization header with the current token.
+func (s *OAuth2TokenSource) Authenticate(req *http.Request) (err error) {
+	t, err := s.Token(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(authorizationHeader, bearerPrefix+t.AccessToken)
+	return nil
+}
+
+// Challenge discards the token used for the request - unless it has
+// already been replaced - and retries with a new token.
+func (s *OAuth2TokenSource) Challenge(req *http.Request, res Response) (retry bool, err error) {
+	s.lock.Lock()
+	if s.token != nil && req.Header.Get(authorizationHeader) == bearerPrefix+s.token.AccessToken {
+		s.token = nil
+	}
+	s.lock.Unlock()
+
+	return true, nil
+}
+
+// Checks if the cached token exists and isn't about to expire. Must be
+// called with the lock held.
+func (s *OAuth2TokenSource) valid() bool {
+	if s.token == nil {
+		return false
+	}
+
+	return s.token.Expiry.IsZero() || time.Now().Add(s.config.ExpiryDelta).Before(s.token.Expiry)
+}
+
+// Requests a new token from the token endpoint.
+func (s *OAuth2TokenSource) fetch(ctx context.Context, refreshToken string) (t *OAuth2Token, err error) {
+	values := url.Values{}
+	if refreshToken != "" {
+		values.Set("grant_type", "refresh_token")
+		values.Set("refresh_token", refreshToken)
+	} else {
+		values.Set("grant_type", "client_credentials")
+	}
+
+	if len(s.config.Scopes) > 0 {
+		values.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+
+	opts := []RequestOption{DecodeErrors[OAuth2ErrorBody]()}
+	if s.config.AuthInBody {
+		values.Set("client_id", s.config.ClientID)
+		values.Set("client_secret", s.config.ClientSecret)
+
+		// Replaces the authenticator of the requester, which may be this
+		// token source waiting for the fetch.
+		opts = append(opts, WithAuthenticator(nil))
+	} else {
+		opts = append(opts, WithAuthenticator(BasicAuth{
+			Username: url.QueryEscape(s.config.ClientID),
+			Password: url.QueryEscape(s.config.ClientSecret),
+		}))
+	}
+
+	start := time.Now()
+	res, err := doJSON[oauth2TokenResponse](ctx, s.config.Requester, http.MethodPost, s.config.TokenURL, nil, ValuesBody(values), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.AccessToken == "" {
+		return nil, NoAccessTokenErr
+	}
+
+	t = &OAuth2Token{
+		AccessToken:  res.AccessToken,
+		TokenType:    res.TokenType,
+		RefreshToken: res.RefreshToken,
+	}
+
+	if res.ExpiresIn > 0 {
+		t.Expiry = start.Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+
+	return t, nil
+}
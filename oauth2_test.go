@@ -0,0 +1,132 @@
+package walgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOAuth2Concurrent(t *testing.T) {
+	server := newTokenServer(3600)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2Config{TokenURL: server.URL + "/token", ClientID: "client", ClientSecret: "secret"})
+	r := NewRequester(http.DefaultClient, "", "", WithAuthenticator(source))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := r.Get(server.URL+"/api", nil)
+			if err != nil || res.Code() != http.StatusOK {
+				t.Error("Request failed:", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if server.fetches != 1 {
+		t.Fatal("Token should be fetched once:", server.fetches)
+	}
+}
+
+func TestOAuth2Expiry(t *testing.T) {
+	server := newTokenServer(5)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2Config{TokenURL: server.URL + "/token", ClientID: "client", ClientSecret: "secret"})
+	for i := 1; i <= 2; i++ {
+		token, err := source.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if token.AccessToken != fmt.Sprintf("token%d", i) {
+			t.Fatal("Token within the expiry delta should be refreshed:", token.AccessToken)
+		}
+	}
+
+	if server.grants[0] != "client_credentials" || server.grants[1] != "refresh_token" {
+		t.Fatal("Unexpected grants:", server.grants)
+	}
+}
+
+func TestOAuth2Challenge(t *testing.T) {
+	server := newTokenServer(3600)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2Config{TokenURL: server.URL + "/token", ClientID: "client", ClientSecret: "secret"})
+	if _, err := source.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another client revokes the token by fetching a new one.
+	atomic.AddInt32(&server.fetches, 1)
+
+	res, err := Get(server.URL+"/api", nil, WithAuthenticator(source))
+	if err != nil || res.Code() != http.StatusOK {
+		t.Fatal("Request should be retried with a new token:", err, res.Code())
+	}
+}
+
+func TestOAuth2OwnRequester(t *testing.T) {
+	server := newTokenServer(3600)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2Config{
+		TokenURL:     server.URL + "/token",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AuthInBody:   true,
+		FetchTimeout: time.Second,
+	})
+	r := NewRequester(http.DefaultClient, "", "", WithAuthenticator(source))
+	source.config.Requester = r
+
+	res, err := r.Get(server.URL+"/api", nil)
+	if err != nil || res.Code() != http.StatusOK {
+		t.Fatal("Token request should not authenticate with the token source:", err)
+	}
+}
+
+func TestOAuth2Error(t *testing.T) {
+	server := newTokenServer(3600)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2Config{TokenURL: server.URL + "/token", ClientID: "client", ClientSecret: "wrong"})
+	_, err := source.Token(context.Background())
+
+	var jsonErr *JSONError[OAuth2ErrorBody]
+	if !errors.As(err, &jsonErr) || jsonErr.Value.Code != "invalid_client" {
+		t.Fatal("Expected OAuth2 error:", err)
+	}
+}
+
+func TestOAuth2CancelledCaller(t *testing.T) {
+	server := newTokenServer(3600)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2Config{TokenURL: server.URL + "/token", ClientID: "client", ClientSecret: "secret"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+	defer cancel()
+
+	if _, err := source.Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("Caller should stop waiting at its deadline:", err)
+	}
+
+	token, err := source.Token(context.Background())
+	if err != nil || token.AccessToken != "token1" {
+		t.Fatal("The fetch should go on for other callers:", token, err)
+	}
+
+	if server.fetches != 1 {
+		t.Fatal("Token should be fetched once:", server.fetches)
+	}
+}
@@ -0,0 +1,74 @@
+package walgo
+
+import (
+	"net/http"
+)
+
+// RequestOption customises a single outgoing request. Options given to
+// NewRequester are applied to every request before the options given to
+// the individual call, so a per-call option can override a default.
+type RequestOption func(o *requestOptions)
+
+// Holds the result of applying a list of RequestOptions.
+type requestOptions struct {
+	headerEdits  []func(h http.Header)
+	allowRetry   bool
+	statusErrors bool
+	decodeError  func(statusErr *StatusError) error
+	stream       bool
+	maxBodySize  int64
+
+	uploadProgress ProgressFunc
+	authenticator  Authenticator
+	signer         Signer
+}
+
+// WithHeader sets the header to the given value, replacing any value that
+// is already present - including the headers set by the requester itself
+// (User-Agent, Authorization and Content-Type).
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.headerEdits = append(o.headerEdits, func(h http.Header) {
+			h.Set(key, value)
+		})
+	}
+}
+
+// AddHeader adds the value to the header keeping any value that is already
+// present.
+func AddHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.headerEdits = append(o.headerEdits, func(h http.Header) {
+			h.Add(key, value)
+		})
+	}
+}
+
+// RemoveHeader removes the header from the request - including the headers
+// set by the requester itself.
+func RemoveHeader(key string) RequestOption {
+	return func(o *requestOptions) {
+		o.headerEdits = append(o.headerEdits, func(h http.Header) {
+			h.Del(key)
+		})
+	}
+}
+
+// Applies the default options followed by the per-call options.
+func buildRequestOptions(defaults, opts []RequestOption) (o *requestOptions) {
+	o = &requestOptions{}
+	for _, opt := range defaults {
+		opt(o)
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Applies the header edits to the given header in the order they were added.
+func (o *requestOptions) applyHeaders(h http.Header) {
+	for _, edit := range o.headerEdits {
+		edit(h)
+	}
+}
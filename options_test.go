@@ -0,0 +1,72 @@
+package walgo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDefaultHeaders(t *testing.T) {
+	var headers http.Header
+	server := newHeaderServer(&headers)
+	defer server.Close()
+
+	r := NewRequester(http.DefaultClient, "Walgo Test", "", WithHeader("Accept", "application/json"))
+	_, err := r.Get(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("Accept") != "application/json" {
+		t.Fatal("Default header not sent:", headers.Get("Accept"))
+	}
+}
+
+func TestPerRequestHeaders(t *testing.T) {
+	var headers http.Header
+	server := newHeaderServer(&headers)
+	defer server.Close()
+
+	r := NewRequester(http.DefaultClient, "Walgo Test", "test123", WithHeader("Accept", "application/json"))
+	_, err := r.Get(server.URL, nil,
+		WithHeader("Accept", "text/plain"),
+		AddHeader("X-Request-ID", "1"),
+		AddHeader("X-Request-ID", "2"),
+		RemoveHeader("Authorization"),
+		WithHeader("User-Agent", "Other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("Accept") != "text/plain" {
+		t.Fatal("Default header not overridden:", headers.Get("Accept"))
+	}
+
+	if ids := headers.Values("X-Request-ID"); len(ids) != 2 {
+		t.Fatal("Added headers not sent:", ids)
+	}
+
+	if headers.Get("Authorization") != "" {
+		t.Fatal("Authorization header not removed.")
+	}
+
+	if headers.Get("User-Agent") != "Other" {
+		t.Fatal("User-Agent not overridden:", headers.Get("User-Agent"))
+	}
+}
+
+func TestRateLimitRequesterHeaders(t *testing.T) {
+	var headers http.Header
+	server := newHeaderServer(&headers)
+	defer server.Close()
+
+	r := NewRateLimitRequester(defaultRequester, 1, time.Hour)
+	_, err := r.PostRaw(server.URL, nil, []byte("data"), WithHeader("If-None-Match", "\"abc\""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("If-None-Match") != "\"abc\"" {
+		t.Fatal("Header not passed through:", headers.Get("If-None-Match"))
+	}
+}
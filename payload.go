@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/url"
+	"strings"
 	"sync"
 )
 
@@ -20,10 +22,29 @@ const (
 type payload struct {
 	contentType string
 	data        []byte
+
+	// Streamed payloads have open set instead of data. It is called once
+	// when the request is sent and the returned body is closed by the
+	// HTTP client. The length is -1 if it is unknown. If the payload is
+	// never sent, close releases the files or reader it streams from.
+	//
+	// If replay is set open can be called for every attempt and close is
+	// always called when the request is done.
+	open   func() io.ReadCloser
+	close  func()
+	length int64
+	used   *sync.Once
+	replay bool
 }
 
 // MultipartPayload holds data for values and files that can be used when
-// making requests with a multipart body.
+// making requests with a multipart body. The body is streamed while the
+// request is sent so files are never held in memory. The files are closed
+// when they have been sent.
+//
+// If all the files are seekable (like *os.File) the length of the body is
+// sent and the body can be sent again, e.g. on retries and redirects.
+// Otherwise it is sent chunked and only once.
 type MultipartPayload struct {
 	values map[string]string
 	files  map[string]FormFile
@@ -38,6 +59,69 @@ type FormFile struct {
 	Name string
 }
 
+// Body is the request body given to Do. It is created using JsonBody,
+// RawBody, ValuesBody, MultipartBody or ReaderBody.
+type Body interface {
+	build() (p *payload, err error)
+}
+
+type jsonBody struct{ v interface{} }
+type rawBody struct{ data []byte }
+type valuesBody struct{ v url.Values }
+type multipartBody struct{ m *MultipartPayload }
+type readerBody struct {
+	r      io.Reader
+	length int64
+}
+
+// JsonBody creates a Body with the given interface type encoded as JSON.
+func JsonBody(v interface{}) Body {
+	return jsonBody{v}
+}
+
+// RawBody creates a Body with the supplied bytes.
+func RawBody(data []byte) Body {
+	return rawBody{data}
+}
+
+// ValuesBody creates a Body with the supplied values urlencoded.
+func ValuesBody(v url.Values) Body {
+	return valuesBody{v}
+}
+
+// MultipartBody creates a Body with the supplied multipart payload encoded.
+func MultipartBody(m *MultipartPayload) Body {
+	return multipartBody{m}
+}
+
+// ReaderBody creates a Body streamed from the reader. The length is the
+// number of bytes the reader provides - or -1 if it is unknown. If the
+// reader is an io.ReadCloser it is closed when the request is done, even if
+// the body was never sent.
+func ReaderBody(r io.Reader, length int64) Body {
+	return readerBody{r, length}
+}
+
+func (b jsonBody) build() (p *payload, err error) {
+	return createJsonPayload(b.v)
+}
+
+func (b rawBody) build() (p *payload, err error) {
+	return payloadFromRawData(b.data), nil
+}
+
+func (b valuesBody) build() (p *payload, err error) {
+	return payloadFromValues(b.v), nil
+}
+
+func (b multipartBody) build() (p *payload, err error) {
+	return payloadFromMultipart(b.m)
+}
+
+func (b readerBody) build() (p *payload, err error) {
+	return payloadFromReader(b.r, b.length), nil
+}
+
 func (p *payload) getContentType() (t string) {
 	return p.contentType
 }
@@ -46,6 +130,33 @@ func (p *payload) getData() (d []byte) {
 	return p.data
 }
 
+// Checks if the payload can be sent more than once.
+func (p *payload) replayable() bool {
+	return p == nil || p.open == nil || p.replay
+}
+
+// Opens the body of a streamed payload. It returns nil if the payload has
+// already been opened or released.
+func (p *payload) openBody() (body io.ReadCloser) {
+	if p.replay {
+		return p.open()
+	}
+
+	p.used.Do(func() {
+		body = p.open()
+	})
+	return body
+}
+
+// Closes the sources of a streamed payload unless it has been opened, in
+// which case the HTTP client closes them. Sources of replayable payloads
+// are always closed.
+func (p *payload) release() {
+	if p != nil && p.open != nil {
+		p.used.Do(p.close)
+	}
+}
+
 // Creates a payload from form values.
 func payloadFromValues(v url.Values) (p *payload) {
 	return &payload{
@@ -54,13 +165,14 @@ func payloadFromValues(v url.Values) (p *payload) {
 	}
 }
 
-// Creates a payload from an interface type by encoding it as JSON.
+// Creates a payload from an interface type by encoding it as JSON. It is
+// sent with the application/json Content-Type.
 func createJsonPayload(v interface{}) (p *payload, err error) {
 	data, err := json.Marshal(v)
 	if err != nil {
 		return nil, err
 	} else {
-		return &payload{data: data}, nil
+		return &payload{data: data, contentType: jsonContentType}, nil
 	}
 }
 
@@ -69,38 +181,177 @@ func payloadFromRawData(d []byte) (p *payload) {
 	return &payload{data: d, contentType: octetStreamContentType}
 }
 
-// Creates a payload from MultipartPayload - encodes files and values.
+// Creates a payload from MultipartPayload. The payload is streamed - the
+// values and files are encoded through a pipe while the request is sent.
+// If the files can be rewound the payload is replayable.
 func payloadFromMultipart(m *MultipartPayload) (p *payload, err error) {
-	buffer := &bytes.Buffer{}
-	writer := multipart.NewWriter(buffer)
-	defer writer.Close()
-
+	m.lock.Lock()
+	values := make(map[string]string, len(m.values))
 	for k, v := range m.values {
-		err := writer.WriteField(k, v)
+		values[k] = v
+	}
+	files := make(map[string]FormFile, len(m.files))
+	for k, v := range m.files {
+		files[k] = v
+	}
+	m.lock.Unlock()
+
+	writer := multipart.NewWriter(ioutil.Discard)
+	boundary := writer.Boundary()
+
+	offsets, size, ok := fileOffsets(files)
+	if !ok {
+		return &payload{
+			contentType: writer.FormDataContentType(),
+			length:      -1,
+			open: func() io.ReadCloser {
+				reader, pipe := io.Pipe()
+				go func() {
+					err := writeMultipart(pipe, boundary, values, files)
+					closeFiles(files)
+					pipe.CloseWithError(err)
+				}()
+				return reader
+			},
+			close: func() {
+				closeFiles(files)
+			},
+			used: &sync.Once{},
+		}, nil
+	}
+
+	// The length is the size of the files plus the encoding around them.
+	empty := make(map[string]FormFile, len(files))
+	for k, v := range files {
+		empty[k] = FormFile{File: ioutil.NopCloser(strings.NewReader("")), Name: v.Name}
+	}
+	encoding := &bytes.Buffer{}
+	if err = writeMultipart(encoding, boundary, values, empty); err != nil {
+		closeFiles(files)
+		return nil, err
+	}
+
+	// Only one body is written at a time as they share the files.
+	lock := &sync.Mutex{}
+
+	return &payload{
+		contentType: writer.FormDataContentType(),
+		length:      int64(encoding.Len()) + size,
+		open: func() io.ReadCloser {
+			reader, pipe := io.Pipe()
+			go func() {
+				lock.Lock()
+				defer lock.Unlock()
+
+				err := rewindFiles(files, offsets)
+				if err == nil {
+					err = writeMultipart(pipe, boundary, values, files)
+				}
+				pipe.CloseWithError(err)
+			}()
+			return reader
+		},
+		close: func() {
+			lock.Lock()
+			defer lock.Unlock()
+			closeFiles(files)
+		},
+		used:   &sync.Once{},
+		replay: true,
+	}, nil
+}
+
+// Finds the offsets the files start at and their total size. It returns
+// false if a file can't be rewound.
+func fileOffsets(files map[string]FormFile) (offsets map[string]int64, size int64, ok bool) {
+	offsets = make(map[string]int64, len(files))
+	for k, v := range files {
+		seeker, isSeeker := v.File.(io.Seeker)
+		if !isSeeker {
+			return nil, 0, false
+		}
+
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, false
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
 		if err != nil {
-			return nil, err
+			return nil, 0, false
 		}
+		if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, false
+		}
+
+		offsets[k] = start
+		size += end - start
 	}
 
-	for k, v := range m.files {
-		if file, err := writer.CreateFormFile(k, v.Name); err == nil {
-			defer v.File.Close()
-			_, err2 := io.Copy(file, v.File)
-			if err2 != nil {
-				return nil, err
-			}
-		} else {
-			return nil, err
+	return offsets, size, true
+}
+
+// Moves the files back to their offsets.
+func rewindFiles(files map[string]FormFile, offsets map[string]int64) (err error) {
+	for k, v := range files {
+		if _, err = v.File.(io.Seeker).Seek(offsets[k], io.SeekStart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encodes the values and files as multipart data to the writer.
+func writeMultipart(w io.Writer, boundary string, values map[string]string, files map[string]FormFile) (err error) {
+	writer := multipart.NewWriter(w)
+	if err = writer.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for k, v := range values {
+		if err = writer.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+
+	for k, v := range files {
+		file, err := writer.CreateFormFile(k, v.Name)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(file, v.File); err != nil {
+			return err
 		}
 	}
 
-	contentType := writer.FormDataContentType()
-	writer.Close()
+	return writer.Close()
+}
 
+// Creates a streamed payload from a reader with the given length - or -1
+// if the length is unknown.
+func payloadFromReader(r io.Reader, length int64) (p *payload) {
 	return &payload{
-		contentType: contentType,
-		data:        buffer.Bytes(),
-	}, nil
+		contentType: octetStreamContentType,
+		length:      length,
+		open: func() io.ReadCloser {
+			if rc, ok := r.(io.ReadCloser); ok {
+				return rc
+			}
+			return ioutil.NopCloser(r)
+		},
+		close: func() {
+			if rc, ok := r.(io.ReadCloser); ok {
+				rc.Close()
+			}
+		},
+		used: &sync.Once{},
+	}
+}
+
+func closeFiles(files map[string]FormFile) {
+	for _, v := range files {
+		v.File.Close()
+	}
 }
 
 // Checks if the MultipartPayload already has an element with name.
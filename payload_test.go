@@ -2,10 +2,16 @@ package walgo
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestValueMapData(t *testing.T) {
@@ -105,3 +111,201 @@ func testResponse(t *testing.T, res Response, err error) {
 		t.Fatal("No data in response.")
 	}
 }
+
+func TestReaderData(t *testing.T) {
+	info := &uploadInfo{}
+	server := newUploadServer(info)
+	defer server.Close()
+
+	data := strings.Repeat("x", 100000)
+	var progressed, total int64
+	_, err := PostReader(server.URL, nil, strings.NewReader(data), int64(len(data)),
+		UploadProgress(func(p, t int64) { progressed, total = p, t }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(info.body) != data || info.length != int64(len(data)) {
+		t.Fatal("Unexpected upload:", len(info.body), info.length)
+	}
+
+	if progressed != int64(len(data)) || total != int64(len(data)) {
+		t.Fatal("Unexpected progress:", progressed, total)
+	}
+
+	_, err = PutReader(server.URL, nil, strings.NewReader(data), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(info.body) != data || len(info.encoding) != 1 || info.encoding[0] != "chunked" {
+		t.Fatal("Unknown length should be sent chunked:", len(info.body), info.encoding)
+	}
+}
+
+func TestMultipartStream(t *testing.T) {
+	info := &uploadInfo{}
+	server := newUploadServer(info)
+	defer server.Close()
+
+	m := &MultipartPayload{}
+	m.Add("key", "value")
+	file := &closeRecorder{Reader: strings.NewReader(strings.Repeat("f", 10000))}
+	m.AddFile("file", FormFile{File: file, Name: "big.txt"})
+
+	_, err := PostMultipart(server.URL, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.form["key"] != "value" || info.form["file"] != "big.txt:"+strings.Repeat("f", 10000) {
+		t.Fatal("Unexpected form:", info.form["key"], len(info.form["file"]))
+	}
+
+	if !file.closed {
+		t.Fatal("File should be closed after sending.")
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestStreamedRedirect(t *testing.T) {
+	var targetHits int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		http.Redirect(w, r, "/target", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/target", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&targetHits, 1)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for i, send := range []func() (Response, error){
+		func() (Response, error) {
+			return PostReader(server.URL+"/redirect", nil, strings.NewReader("data"), 4)
+		},
+		func() (Response, error) {
+			return PostReader(server.URL+"/redirect", nil, strings.NewReader("data"), -1)
+		},
+		func() (Response, error) {
+			m := &MultipartPayload{}
+			m.AddFile("file", FormFile{File: &closeRecorder{Reader: strings.NewReader("data")}, Name: "file.txt"})
+			return PostMultipart(server.URL+"/redirect", nil, m)
+		},
+	} {
+		res, err := send()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Code() != http.StatusTemporaryRedirect {
+			t.Fatalf("(%d) Redirect needing the streamed body should be returned: %d", i, res.Code())
+		}
+	}
+
+	if hits := atomic.LoadInt32(&targetHits); hits != 0 {
+		t.Fatal("Streamed body should never be replayed empty:", hits)
+	}
+}
+
+type seekRecorder struct {
+	*strings.Reader
+	closed bool
+}
+
+func (c *seekRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMultipartReplay(t *testing.T) {
+	info := &uploadInfo{}
+	upload := newUploadServer(info)
+	defer upload.Close()
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.Redirect(w, r, upload.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	m := &MultipartPayload{}
+	m.Add("key", "value")
+	file := &seekRecorder{Reader: strings.NewReader("file data")}
+	m.AddFile("file", FormFile{File: file, Name: "file.txt"})
+
+	r := NewRetryRequester(defaultRequester, RetryPolicy{RetryServerErrors: true, InitialBackoff: time.Millisecond})
+	res, err := r.PutMultipart(server.URL, nil, m)
+	if err != nil || res.Code() != http.StatusOK {
+		t.Fatal(err)
+	}
+
+	if attempts != 2 || info.form["key"] != "value" || info.form["file"] != "file.txt:file data" {
+		t.Fatal("Body should be sent again on retries and redirects:", attempts, info.form)
+	}
+
+	if info.length <= 0 || len(info.encoding) != 0 {
+		t.Fatal("Length of seekable files should be sent:", info.length, info.encoding)
+	}
+
+	if !file.closed {
+		t.Fatal("File should be closed after sending.")
+	}
+}
+
+func TestStreamedRelease(t *testing.T) {
+	failingAuth := AuthenticatorFunc(func(req *http.Request) error {
+		return errors.New("no credentials")
+	})
+
+	for i, send := range []func(Requester, io.ReadCloser) error{
+		func(r Requester, f io.ReadCloser) error {
+			m := &MultipartPayload{}
+			m.AddFile("file", FormFile{File: f, Name: "file.txt"})
+			_, err := r.PostMultipart("http://example.com", nil, m, WithAuthenticator(failingAuth))
+			return err
+		},
+		func(r Requester, f io.ReadCloser) error {
+			_, err := r.PostReader("http://example.com", nil, f, -1, WithAuthenticator(failingAuth))
+			return err
+		},
+		func(r Requester, f io.ReadCloser) error {
+			m := &MultipartPayload{}
+			m.AddFile("file", FormFile{File: f, Name: "file.txt"})
+			_, err := NewRateLimitRequester(r, 0, time.Hour).PostMultipart("http://example.com", nil, m)
+			return err
+		},
+		func(r Requester, f io.ReadCloser) error {
+			_, err := NewRetryRequester(NewRateLimitRequester(r, 0, time.Hour), RetryPolicy{}).PutReader("http://example.com", nil, f, -1)
+			return err
+		},
+		func(r Requester, f io.ReadCloser) error {
+			_, err := NewRateLimitRequester(r, 0, time.Hour).Do(http.MethodPost, "http://example.com", nil, ReaderBody(f, -1))
+			return err
+		},
+	} {
+		file := &closeRecorder{Reader: strings.NewReader("data")}
+		if err := send(defaultRequester, file); err == nil {
+			t.Fatalf("(%d) Request should fail.", i)
+		}
+
+		if !file.closed {
+			t.Fatalf("(%d) File should be closed when the request isn't sent.", i)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package walgo
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// NoRatePolicyErr is returned by a FirstOf policy without any policies.
+	NoRatePolicyErr = errors.New("No rate policy to resolve the client.")
+)
+
+// Escapes the separator of composite clients so different combinations
+// can't give the same client.
+var compositeEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+
+// Escapes the separator of prefixed clients for the same reason.
+var prefixEscaper = strings.NewReplacer(`\`, `\\`, `:`, `\:`)
+
+// RatePolicyFunc is a RatePolicy resolving the client using a function.
+type RatePolicyFunc func(r *http.Request) (string, error)
+
+// GetClient calls the function.
+func (f RatePolicyFunc) GetClient(r *http.Request) (client string, err error) {
+	return f(r)
+}
+
+type firstOfPolicy []RatePolicy
+
+type compositePolicy []RatePolicy
+
+type prefixedPolicy struct {
+	name   string
+	policy RatePolicy
+}
+
+// FirstOf returns a RatePolicy using the first of the policies that
+// resolves the client, e.g. FirstOf(TokenRatePolicy{}, IPRatePolicy{})
+// limits by token and falls back to the IP address for anonymous clients.
+// If no policy resolves the client the error of the last one is returned.
+// Use Prefixed to keep the clients of the policies apart.
+func FirstOf(policies ...RatePolicy) RatePolicy {
+	return firstOfPolicy(policies)
+}
+
+// Composite returns a RatePolicy combining the clients resolved by all the
+// policies into one, e.g. to limit each user on each route. It fails if
+// any of the policies fails.
+func Composite(policies ...RatePolicy) RatePolicy {
+	return compositePolicy(policies)
+}
+
+// Prefixed returns a RatePolicy putting the name in front of the clients
+// resolved by the policy, so clients of different policies sharing a
+// RateLimitHandler never collide.
+func Prefixed(name string, p RatePolicy) RatePolicy {
+	return prefixedPolicy{name: name, policy: p}
+}
+
+// GetClient returns the client of the first policy that resolves one.
+func (p firstOfPolicy) GetClient(r *http.Request) (client string, err error) {
+	err = NoRatePolicyErr
+	for _, policy := range p {
+		if client, err = policy.GetClient(r); err == nil {
+			return client, nil
+		}
+	}
+
+	return "", err
+}
+
+// GetClient joins the clients of all the policies.
+func (p compositePolicy) GetClient(r *http.Request) (client string, err error) {
+	clients := make([]string, len(p))
+	for i, policy := range p {
+		c, err := policy.GetClient(r)
+		if err != nil {
+			return "", err
+		}
+		clients[i] = compositeEscaper.Replace(c)
+	}
+
+	return strings.Join(clients, "|"), nil
+}
+
+// GetClient returns the client of the policy with the name in front.
+func (p prefixedPolicy) GetClient(r *http.Request) (client string, err error) {
+	client, err = p.policy.GetClient(r)
+	if err != nil {
+		return "", err
+	}
+
+	return prefixEscaper.Replace(p.name) + ":" + prefixEscaper.Replace(client), nil
+}
@@ -0,0 +1,105 @@
+package walgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var routePolicy = RatePolicyFunc(func(r *http.Request) (string, error) {
+	return r.URL.Path, nil
+})
+
+func newPolicyRequest(token, path string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if token != "" {
+		req.Header.Set(authorizationHeader, bearerPrefix+token)
+	}
+	return req
+}
+
+func TestFirstOfPolicy(t *testing.T) {
+	p := FirstOf(Prefixed("token", TokenRatePolicy{}), Prefixed("ip", IPRatePolicy{}))
+
+	client, err := p.GetClient(newPolicyRequest("abc", "/"))
+	if err != nil || client != "token:abc" {
+		t.Fatal("Token should be used:", client, err)
+	}
+
+	client, err = p.GetClient(newPolicyRequest("", "/"))
+	if err != nil || client != "ip:192.0.2.1" {
+		t.Fatal("IP should be used for anonymous clients:", client, err)
+	}
+
+	_, err = FirstOf(TokenRatePolicy{}, HeaderRatePolity{Name: "X-Client"}).GetClient(newPolicyRequest("", "/"))
+	if err != NoHeaderValueErr {
+		t.Fatal("Error of the last policy should be returned:", err)
+	}
+
+	_, err = FirstOf().GetClient(newPolicyRequest("", "/"))
+	if err != NoRatePolicyErr {
+		t.Fatal("Empty policy should fail:", err)
+	}
+}
+
+func TestCompositePolicy(t *testing.T) {
+	p := Composite(TokenRatePolicy{}, routePolicy)
+
+	client, err := p.GetClient(newPolicyRequest("abc", "/orders"))
+	if err != nil || client != "abc|/orders" {
+		t.Fatal("Unexpected client:", client, err)
+	}
+
+	_, err = p.GetClient(newPolicyRequest("", "/orders"))
+	if err != NoAuthorizationHeaderErr {
+		t.Fatal("Composite should fail when a policy fails:", err)
+	}
+
+	a, _ := p.GetClient(newPolicyRequest("a|b", "/c"))
+	b, _ := p.GetClient(newPolicyRequest("a", "/b|/c"))
+	if a == b {
+		t.Fatal("Different combinations should give different clients:", a)
+	}
+}
+
+func TestCombinedPolicyRateLimit(t *testing.T) {
+	h := &hitCountHandler{}
+	r := NewRateLimiter(1, time.Hour, FirstOf(
+		Prefixed("user-route", Composite(TokenRatePolicy{}, routePolicy)),
+		Prefixed("ip", IPRatePolicy{}),
+	), h)
+
+	for i, c := range []struct {
+		token string
+		path  string
+		code  int
+	}{
+		{"abc", "/orders", http.StatusOK},
+		{"abc", "/orders", http.StatusTooManyRequests},
+		{"abc", "/users", http.StatusOK},
+		{"def", "/orders", http.StatusOK},
+		{"", "/orders", http.StatusOK},
+		{"", "/users", http.StatusTooManyRequests},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newPolicyRequest(c.token, c.path))
+		if w.Code != c.code {
+			t.Fatalf("(%d) Wrong code (%d) expected: %d", i, w.Code, c.code)
+		}
+	}
+}
+
+func TestPrefixedPolicy(t *testing.T) {
+	a, _ := Prefixed("a", routePolicy).GetClient(newPolicyRequest("", "/b:c"))
+	b, _ := Prefixed("a:/b", routePolicy).GetClient(newPolicyRequest("", "/c"))
+	if a == b {
+		t.Fatal("Different names and clients should give different clients:", a)
+	}
+
+	client, err := Prefixed("ip", IPRatePolicy{}).GetClient(newPolicyRequest("", "/"))
+	if err != nil || client != "ip:192.0.2.1" {
+		t.Fatal("Unexpected client:", client, err)
+	}
+}
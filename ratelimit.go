@@ -1,10 +1,14 @@
 package walgo
 
 import (
+	"container/list"
+	"context"
 	"errors"
-	"net"
+	"fmt"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -34,14 +38,115 @@ var (
 // functions to handle requests or shield http.HandleFunc using the
 // limits provided.
 type RateLimitHandler struct {
-	maxRequests   int
-	duration      time.Duration
-	requestCounts map[string][]int64
-	lock          *sync.Mutex
-	handler       http.Handler
-	policy        RatePolicy
+	algorithm RateLimitAlgorithm
+	limiters  map[string]*list.Element
+	order     *list.List
+	lock      *sync.Mutex
+	handler   http.Handler
+	policy    RatePolicy
+
+	maxClients      int
+	cleanupInterval time.Duration
+	lastCleanup     time.Time
+	janitor         bool
+	headers         RateLimitHeaders
+	onLimited       RejectHandler
+	onPolicyError   RejectHandler
+	failOpen        bool
+	stop            chan struct{}
+	stopOnce        *sync.Once
+	now             func() time.Time
 }
 
+type rateLimitClient struct {
+	client  string
+	limiter Limiter
+}
+
+// RateLimiterConfig holds the settings for a RateLimitHandler.
+type RateLimiterConfig struct {
+	// Limit and Duration allow Limit requests from each client in any
+	// period of Duration, like NewRateLimiter.
+	Limit    int
+	Duration time.Duration
+
+	// Algorithm is used instead of a SlidingLog of Limit and Duration if it
+	// is set.
+	Algorithm RateLimitAlgorithm
+
+	// MaxClients is the maximum number of clients tracked. When it is
+	// reached the least recently seen client is forgotten - even if it
+	// still has requests in its window. 0 means no maximum.
+	MaxClients int
+
+	// CleanupInterval is how often the clients with no requests left in
+	// their window are forgotten. If it is 0, one minute is used.
+	CleanupInterval time.Duration
+
+	// Janitor runs the cleanup in a background goroutine instead of while
+	// handling requests. The goroutine runs until Stop is called.
+	Janitor bool
+
+	// Headers selects the rate limit headers added to the responses. No
+	// headers are added unless a style is selected.
+	Headers RateLimitHeaders
+
+	// OnLimited writes the response to requests over the limit. The error
+	// is RateLimitExceededErr. If it is nil a 429 response is written.
+	OnLimited RejectHandler
+
+	// OnPolicyError writes the response to requests the policy can't
+	// resolve the client of. The error is the one returned by the policy
+	// and the retry time is 0. If it is nil a 429 response is written.
+	OnPolicyError RejectHandler
+
+	// FailOpen lets requests the policy can't resolve the client of
+	// through instead of calling OnPolicyError.
+	FailOpen bool
+}
+
+// RejectHandler writes the response to a request that isn't let through
+// by a RateLimitHandler. The retryAfter is how long the client should wait
+// before trying again.
+type RejectHandler func(w http.ResponseWriter, req *http.Request, err error, retryAfter time.Duration)
+
+// StatusRejectHandler returns a RejectHandler responding with the given
+// status code.
+func StatusRejectHandler(code int) RejectHandler {
+	return func(w http.ResponseWriter, req *http.Request, err error, retryAfter time.Duration) {
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+// The default RejectHandler.
+func rejectTooManyRequests(w http.ResponseWriter, req *http.Request, err error, retryAfter time.Duration) {
+	http.Error(w, "Too many requests.", http.StatusTooManyRequests)
+}
+
+// RateLimitHeaders selects the headers a RateLimitHandler uses to tell
+// clients about their budget. Unless it is NoRateLimitHeaders Retry-After
+// is set on rejected requests as well.
+type RateLimitHeaders int
+
+const (
+	// NoRateLimitHeaders sets no headers. It is the default.
+	NoRateLimitHeaders RateLimitHeaders = iota
+
+	// XRateLimitHeaders sets X-RateLimit-Limit, X-RateLimit-Remaining and
+	// X-RateLimit-Reset. The reset is a unix timestamp.
+	XRateLimitHeaders
+
+	// IETFRateLimitHeaders sets the RateLimit and RateLimit-Policy headers
+	// from the IETF draft. The reset is a number of seconds.
+	IETFRateLimitHeaders
+
+	// AllRateLimitHeaders sets the headers of both dialects.
+	AllRateLimitHeaders
+)
+
+// The name of the policy in the IETF headers.
+const rateLimitPolicyName = `"default"`
+
 // RatePolicy is the common interface for the different rate limiting policies.
 type RatePolicy interface {
 	// GetClient returns a string representing the client using the data in
@@ -49,10 +154,6 @@ type RatePolicy interface {
 	GetClient(*http.Request) (string, error)
 }
 
-// IPRatePolicy rate limits using the clients IP address as client
-// identification.
-type IPRatePolicy struct{}
-
 // TokenRatePolicy rate limits using the Bearer token set en the
 // request Authorization header as client identification.
 type TokenRatePolicy struct{}
@@ -75,13 +176,90 @@ type CookieRatePolicy struct {
 // The rate limiter uses the provided policy and redirects requests within the
 // limit to the given handler (when itself is used as http.Handler).
 func NewRateLimiter(maxRequests int, duration time.Duration, p RatePolicy, handler http.Handler) (r *RateLimitHandler) {
-	return &RateLimitHandler{
-		maxRequests:   maxRequests,
-		duration:      duration,
-		requestCounts: make(map[string][]int64),
-		lock:          &sync.Mutex{},
-		handler:       handler,
-		policy:        p,
+	return NewRateLimiterWithConfig(RateLimiterConfig{Limit: maxRequests, Duration: duration}, p, handler)
+}
+
+// Creates a new rate limiter using the given config. Like NewRateLimiter it
+// uses the provided policy and redirects requests within the limit to the
+// given handler.
+func NewRateLimiterWithConfig(config RateLimiterConfig, p RatePolicy, handler http.Handler) (r *RateLimitHandler) {
+	if config.Algorithm == nil {
+		config.Algorithm = SlidingLog{Limit: config.Limit, Duration: config.Duration}
+	}
+	if config.CleanupInterval == 0 {
+		config.CleanupInterval = time.Minute
+	}
+	if config.OnLimited == nil {
+		config.OnLimited = rejectTooManyRequests
+	}
+	if config.OnPolicyError == nil {
+		config.OnPolicyError = rejectTooManyRequests
+	}
+
+	r = &RateLimitHandler{
+		algorithm:       config.Algorithm,
+		limiters:        make(map[string]*list.Element),
+		order:           list.New(),
+		lock:            &sync.Mutex{},
+		handler:         handler,
+		policy:          p,
+		maxClients:      config.MaxClients,
+		cleanupInterval: config.CleanupInterval,
+		lastCleanup:     time.Now(),
+		janitor:         config.Janitor,
+		headers:         config.Headers,
+		onLimited:       config.OnLimited,
+		onPolicyError:   config.OnPolicyError,
+		failOpen:        config.FailOpen,
+		stop:            make(chan struct{}),
+		stopOnce:        &sync.Once{},
+		now:             time.Now,
+	}
+
+	if r.janitor {
+		go r.runJanitor()
+	}
+
+	return r
+}
+
+// Stop stops the janitor goroutine of the rate limiter. It is safe to call
+// more than once and on rate limiters without a janitor.
+func (r *RateLimitHandler) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+func (r *RateLimitHandler) runJanitor() {
+	ticker := time.NewTicker(r.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.lock.Lock()
+			r.cleanup(r.now())
+			r.lock.Unlock()
+		}
+	}
+}
+
+// Forgets the clients whose limiters are idle. Must be called with the lock
+// held.
+func (r *RateLimitHandler) cleanup(now time.Time) {
+	r.lastCleanup = now
+
+	for e := r.order.Back(); e != nil; {
+		prev := e.Prev()
+		c := e.Value.(*rateLimitClient)
+		if c.limiter.Idle(now) {
+			r.order.Remove(e)
+			delete(r.limiters, c.client)
+		}
+		e = prev
 	}
 }
 
@@ -107,13 +285,6 @@ func (p CookieRatePolicy) GetClient(r *http.Request) (client string, err error)
 	return cookie.Value, nil
 }
 
-// GetClient implements getting the client id string from the request
-// remote IP address value.
-func (p IPRatePolicy) GetClient(r *http.Request) (client string, err error) {
-	client, _, err = net.SplitHostPort(r.RemoteAddr)
-	return client, err
-}
-
 // GetClient implements getting the client id string from the request
 // Authorization headers Bearer token.
 func (p TokenRatePolicy) GetClient(r *http.Request) (client string, err error) {
@@ -129,249 +300,474 @@ func (p TokenRatePolicy) GetClient(r *http.Request) (client string, err error) {
 	return strings.TrimPrefix(authorization, bearerPrefix), nil
 }
 
-func (r *RateLimitHandler) allowed(client string) (allowed bool) {
+func (r *RateLimitHandler) allowed(client string) (res RateLimitResult) {
 	r.lock.Lock()
+	defer r.lock.Unlock()
 
-	if counts, ok := r.requestCounts[client]; ok {
-		var i int
-		var ts int64
-
-		for i, ts = range counts {
-			if ts >= time.Now().UnixNano()-int64(r.duration) {
-				break
-			}
-		}
+	now := r.now()
+	if !r.janitor && now.Sub(r.lastCleanup) >= r.cleanupInterval {
+		r.cleanup(now)
+	}
 
-		newCounts := counts[i:]
+	return r.limiter(client).Allow(now)
+}
 
-		if len(newCounts) >= r.maxRequests {
-			allowed = false
-		} else {
-			allowed = true
-			newCounts = append(newCounts, time.Now().UnixNano())
-		}
+// Returns the limiter of the client, creating it if needed, and marks the
+// client as the most recently seen. Must be called with the lock held.
+func (r *RateLimitHandler) limiter(client string) Limiter {
+	if e, ok := r.limiters[client]; ok {
+		r.order.MoveToFront(e)
+		return e.Value.(*rateLimitClient).limiter
+	}
 
-		r.requestCounts[client] = newCounts
-	} else {
-		counts := []int64{time.Now().UnixNano()}
-		r.requestCounts[client] = counts
+	c := &rateLimitClient{client: client, limiter: r.algorithm.NewLimiter()}
+	r.limiters[client] = r.order.PushFront(c)
 
-		if r.maxRequests > 1 {
-			allowed = true
-		} else {
-			allowed = false
-		}
+	for r.maxClients > 0 && r.order.Len() > r.maxClients {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.limiters, oldest.Value.(*rateLimitClient).client)
 	}
 
-	r.lock.Unlock()
-	return allowed
+	return c.limiter
 }
 
 // ServeHTTP is implemented to satisfy the http.Handler interface. It checks
 // if the request should be allowed through using the policy and if that is
 // the case it forwards the call to the internal handler.
 func (r *RateLimitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if client, err := r.policy.GetClient(req); err == nil {
-		if r.allowed(client) {
-			r.handler.ServeHTTP(w, req)
-			return
-		}
+	if r.check(w, req) {
+		r.handler.ServeHTTP(w, req)
 	}
-
-	http.Error(w, "Too many requests.", http.StatusTooManyRequests)
 }
 
 // LimitHandlerFunc takes a http.HandlerFunc and wraps it in a rate limited
 // version.
 func (r *RateLimitHandler) LimitHandlerFunc(hf http.HandlerFunc) (h http.HandlerFunc) {
 	return func(w http.ResponseWriter, req *http.Request) {
-		if client, err := r.policy.GetClient(req); err == nil {
-			if r.allowed(client) {
-				hf(w, req)
-				return
-			}
+		if r.check(w, req) {
+			hf(w, req)
 		}
+	}
+}
+
+// Checks the limit of the client making the request and sets the rate
+// limit headers. If the request isn't allowed the rejection is written and
+// false is returned.
+func (r *RateLimitHandler) check(w http.ResponseWriter, req *http.Request) (allowed bool) {
+	client, err := r.policy.GetClient(req)
+	if err != nil {
+		if r.failOpen {
+			return true
+		}
+		r.onPolicyError(w, req, err, 0)
+		return false
+	}
+
+	res := r.allowed(client)
+	r.setHeaders(w.Header(), res, r.now())
 
-		http.Error(w, "Too many requests.", http.StatusTooManyRequests)
+	if !res.Allowed {
+		r.onLimited(w, req, RateLimitExceededErr, res.RetryAfter)
+		return false
 	}
+
+	return true
+}
+
+// Sets the rate limit headers of the selected dialect from the result.
+func (r *RateLimitHandler) setHeaders(h http.Header, res RateLimitResult, now time.Time) {
+	if r.headers == NoRateLimitHeaders {
+		return
+	}
+
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.FormatInt(ceilSeconds(res.RetryAfter), 10))
+	}
+
+	if r.headers == XRateLimitHeaders || r.headers == AllRateLimitHeaders {
+		h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(res.Reset+time.Second-1).Unix(), 10))
+	}
+
+	if r.headers == IETFRateLimitHeaders || r.headers == AllRateLimitHeaders {
+		h.Set("RateLimit-Policy", fmt.Sprintf("%s;q=%d;w=%d", rateLimitPolicyName, res.Limit, ceilSeconds(res.Window)))
+		h.Set("RateLimit", fmt.Sprintf("%s;r=%d;t=%d", rateLimitPolicyName, res.Remaining, ceilSeconds(res.Reset)))
+	}
+}
+
+// Rounds the duration up to whole seconds.
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
 }
 
 // RateLimitRequester is used for managing and limiting outgoing requests.
+// Requests within the limit are forwarded to the internal Requester. If the
+// context of a request is already done its error is returned without using
+// up any of the limit.
 type RateLimitRequester struct {
-	requester     Requester
-	limit         int
-	duration      time.Duration
-	requestCounts []int64
-	lock          *sync.Mutex
+	requestMethods
+
+	requester Requester
+
+	key     RateLimitKeyFunc
+	rule    RateLimitRule
+	rules   map[string]RateLimitRule
+	windows map[string]*rateLimitWindow
+
+	idleTimeout  time.Duration
+	lastEviction time.Time
+
+	blocking bool
+	maxWait  time.Duration
+
+	lock *sync.Mutex
+	now  func() time.Time
+}
+
+// RateLimitRule allows Limit requests per Duration.
+type RateLimitRule struct {
+	Limit    int
+	Duration time.Duration
+
+	// Algorithm is used instead of a SlidingLog of Limit and Duration if it
+	// is set.
+	Algorithm RateLimitAlgorithm
+}
+
+// NewLimiter creates a limiter for the rule.
+func (r RateLimitRule) NewLimiter() Limiter {
+	if r.Algorithm != nil {
+		return r.Algorithm.NewLimiter()
+	}
+	return SlidingLog{Limit: r.Limit, Duration: r.Duration}.NewLimiter()
+}
+
+// RateLimitKeyFunc returns the key an outgoing request is limited under.
+// Requests with the same key share a limit.
+type RateLimitKeyFunc func(u *url.URL) string
+
+// HostKey limits the requests for each host separately.
+func HostKey(u *url.URL) string {
+	return u.Host
+}
+
+// HostPathKey limits the requests for each host and path prefix
+// separately. The key is the host followed by the longest of the prefixes
+// matching the path - or just the host if none match. Prefixes match whole
+// path segments, so "/api" matches "/api" and "/api/users" but not
+// "/apiary".
+func HostPathKey(prefixes ...string) RateLimitKeyFunc {
+	return func(u *url.URL) string {
+		match := ""
+		for _, prefix := range prefixes {
+			if hasPathPrefix(u.Path, prefix) && len(prefix) > len(match) {
+				match = prefix
+			}
+		}
+		return u.Host + match
+	}
+}
+
+// Checks if the path starts with the prefix on a segment boundary.
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// KeyedRateLimitConfig holds the settings for a RateLimitRequester with a
+// separate limit for each key.
+type KeyedRateLimitConfig struct {
+	// Key resolves the key of a request. If it is nil HostKey is used.
+	Key RateLimitKeyFunc
+
+	// Rules holds the rules for specific keys. Keys without a rule use
+	// Default.
+	Rules   map[string]RateLimitRule
+	Default RateLimitRule
+
+	// IdleTimeout is how long a key must be unused and have an empty window
+	// before it is forgotten. If it is 0, one minute is used.
+	IdleTimeout time.Duration
+
+	// Blocking makes requests wait for a free slot like the requester
+	// created by NewBlockingRateLimitRequester.
+	Blocking bool
+	MaxWait  time.Duration
 }
 
 // Creates a new Requester that limits requets according to the given limit
 // and duration. Requets inside the limit is forwarded to the internal
 // requester.
 func NewRateLimitRequester(r Requester, limit int, duration time.Duration) (lr Requester) {
-	return &RateLimitRequester{
-		requester:     r,
-		limit:         limit,
-		duration:      duration,
-		requestCounts: nil,
-		lock:          &sync.Mutex{},
+	l := &RateLimitRequester{
+		requester: r,
+		rule:      RateLimitRule{Limit: limit, Duration: duration},
+		windows:   make(map[string]*rateLimitWindow),
+		lock:      &sync.Mutex{},
+		now:       time.Now,
 	}
+	l.send = l.makeRequest
+	return l
 }
 
-func (l *RateLimitRequester) allowed() (allowed bool) {
-	l.lock.Lock()
-
-	var i int
-	var ts int64
+// Creates a new Requester that limits requests like NewRateLimitRequester
+// but waits for a free slot instead of failing when the limit is reached.
+// Waiting requests are served in the order they arrived. A request fails
+// with RateLimitExceededErr if no slot frees up within maxWait or before the
+// deadline of its context. A maxWait of zero or less waits as long as the
+// context allows.
+func NewBlockingRateLimitRequester(r Requester, limit int, duration, maxWait time.Duration) (lr Requester) {
+	l := &RateLimitRequester{
+		requester: r,
+		rule:      RateLimitRule{Limit: limit, Duration: duration},
+		windows:   make(map[string]*rateLimitWindow),
+		blocking:  true,
+		maxWait:   maxWait,
+		lock:      &sync.Mutex{},
+		now:       time.Now,
+	}
+	l.send = l.makeRequest
+	return l
+}
 
-	for i, ts = range l.requestCounts {
-		if ts >= time.Now().UnixNano()-int64(l.duration) {
-			break
-		}
+// Creates a new Requester that limits requests using the given algorithm.
+func NewAlgorithmRateLimitRequester(r Requester, a RateLimitAlgorithm) (lr Requester) {
+	l := &RateLimitRequester{
+		requester: r,
+		rule:      RateLimitRule{Algorithm: a},
+		windows:   make(map[string]*rateLimitWindow),
+		lock:      &sync.Mutex{},
+		now:       time.Now,
 	}
+	l.send = l.makeRequest
+	return l
+}
 
-	var newCounts []int64
-	if len(l.requestCounts) > 0 {
-		newCounts = l.requestCounts[i:]
+// Creates a new Requester that keeps a separate limit for each key of the
+// requests, e.g. for each host.
+func NewKeyedRateLimitRequester(r Requester, config KeyedRateLimitConfig) (lr Requester) {
+	if config.Key == nil {
+		config.Key = HostKey
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = time.Minute
 	}
 
-	if len(newCounts) >= l.limit {
-		allowed = false
-	} else {
-		allowed = true
-		newCounts = append(newCounts, time.Now().UnixNano())
+	l := &RateLimitRequester{
+		requester:    r,
+		key:          config.Key,
+		rule:         config.Default,
+		rules:        config.Rules,
+		windows:      make(map[string]*rateLimitWindow),
+		idleTimeout:  config.IdleTimeout,
+		lastEviction: time.Now(),
+		blocking:     config.Blocking,
+		maxWait:      config.MaxWait,
+		lock:         &sync.Mutex{},
+		now:          time.Now,
 	}
+	l.send = l.makeRequest
+	return l
+}
 
-	l.requestCounts = newCounts
+// rateLimitWindow is the limiter of the requests made under a key, along
+// with the requests waiting for a slot.
+type rateLimitWindow struct {
+	limiter  Limiter
+	waiters  []*rateLimitWaiter
+	lastUsed time.Time
+}
 
-	l.lock.Unlock()
-	return allowed
+// rateLimitWaiter is a request queued for a slot. The ready channel is
+// closed when the waiter reaches the front of the queue.
+type rateLimitWaiter struct {
+	ready chan struct{}
 }
 
-// Get forwards the request to the internal Requester if it is within the
-// rate limit.
-func (l *RateLimitRequester) Get(url string, p ParameterMap) (res Response, err error) {
-	if l.allowed() {
-		return l.requester.Get(url, p)
-	} else {
-		return nil, RateLimitExceededErr
+// Returns the window for the key, creating it if needed. Must be called
+// with the lock held.
+func (l *RateLimitRequester) window(key string, now time.Time) *rateLimitWindow {
+	l.evict(now)
+
+	w, ok := l.windows[key]
+	if !ok {
+		rule, ok := l.rules[key]
+		if !ok {
+			rule = l.rule
+		}
+		w = &rateLimitWindow{limiter: rule.NewLimiter()}
+		l.windows[key] = w
 	}
+
+	w.lastUsed = now
+	return w
 }
 
-// Post forwards the request to the internal Requester if it is within the
-// rate limit.
-func (l *RateLimitRequester) Post(url string, p ParameterMap) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.Post(url, p)
-	} else {
-		return nil, RateLimitExceededErr
+// Forgets the keys that have been idle for the idle timeout. The keys are
+// checked at most once per idle timeout to keep the cost amortised. Must be
+// called with the lock held.
+func (l *RateLimitRequester) evict(now time.Time) {
+	if l.idleTimeout <= 0 || now.Sub(l.lastEviction) < l.idleTimeout {
+		return
 	}
-}
+	l.lastEviction = now
 
-// PostJson forwards the request to the internal Requester if it is within the
-// rate limit.
-func (l *RateLimitRequester) PostJson(url string, p ParameterMap, v interface{}) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PostJson(url, p, v)
-	} else {
-		return nil, RateLimitExceededErr
+	for key, w := range l.windows {
+		if len(w.waiters) == 0 && now.Sub(w.lastUsed) >= l.idleTimeout && w.limiter.Idle(now) {
+			delete(l.windows, key)
+		}
 	}
 }
 
-// PostRaw forwards the request to the internal Requester if it is within the
-// rate limit.
-func (l *RateLimitRequester) PostRaw(url string, p ParameterMap, data []byte) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PostRaw(url, p, data)
-	} else {
-		return nil, RateLimitExceededErr
+// Resolves the key of the request.
+func (l *RateLimitRequester) keyOf(urlStr string) (key string, err error) {
+	if l.key == nil {
+		return "", nil
 	}
-}
 
-// PostMultipart forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) PostMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PostMultipart(url, p, m)
-	} else {
-		return nil, RateLimitExceededErr
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return "", err
 	}
+
+	return l.key(u), nil
 }
 
-// PostValues forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) PostValues(url string, p ParameterMap, v url.Values) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PostValues(url, p, v)
-	} else {
-		return nil, RateLimitExceededErr
+func (l *RateLimitRequester) allowed(key string) (allowed bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	w := l.window(key, now)
+	if len(w.waiters) > 0 {
+		return false
 	}
+
+	return w.limiter.Allow(now).Allowed
 }
 
-// Put forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) Put(url string, p ParameterMap) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.Put(url, p)
-	} else {
-		return nil, RateLimitExceededErr
+// Checks the context before checking the limit so a request that is
+// already cancelled doesn't take up a slot.
+func (l *RateLimitRequester) allowedContext(ctx context.Context, urlStr string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
 	}
-}
 
-// PutJson forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) PutJson(url string, p ParameterMap, v interface{}) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PutJson(url, p, v)
-	} else {
-		return nil, RateLimitExceededErr
+	key, err := l.keyOf(urlStr)
+	if err != nil {
+		return err
 	}
-}
 
-// PutRaw forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) PutRaw(url string, p ParameterMap, data []byte) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PutRaw(url, p, data)
-	} else {
-		return nil, RateLimitExceededErr
+	if l.blocking {
+		return l.wait(ctx, key)
 	}
-}
 
-// PutMultipart forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) PutMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PutMultipart(url, p, m)
-	} else {
-		return nil, RateLimitExceededErr
+	if !l.allowed(key) {
+		return RateLimitExceededErr
 	}
+
+	return nil
 }
 
-// PutValues forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) PutValues(url string, p ParameterMap, v url.Values) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.PutValues(url, p, v)
-	} else {
-		return nil, RateLimitExceededErr
+// Queues the request and waits until it is at the front of the queue for
+// its key and a slot is free. Only the waiter at the front watches the
+// window so slots are handed out in arrival order.
+func (l *RateLimitRequester) wait(ctx context.Context, key string) (err error) {
+	var deadline time.Time
+	if l.maxWait > 0 {
+		deadline = l.now().Add(l.maxWait)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+
+	waiter := &rateLimitWaiter{ready: make(chan struct{})}
+
+	l.lock.Lock()
+	w := l.window(key, l.now())
+	w.waiters = append(w.waiters, waiter)
+	if len(w.waiters) == 1 {
+		close(waiter.ready)
+	}
+	l.lock.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
 	}
+
+	select {
+	case <-waiter.ready:
+	case <-ctx.Done():
+		l.leave(w, waiter)
+		return ctx.Err()
+	case <-timeout:
+		l.leave(w, waiter)
+		return RateLimitExceededErr
+	}
+
+	for {
+		l.lock.Lock()
+		now := l.now()
+		res := w.limiter.Allow(now)
+
+		if res.Allowed {
+			w.lastUsed = now
+			w.leave(waiter)
+			l.lock.Unlock()
+			return nil
+		}
+
+		if res.Limit <= 0 || (!deadline.IsZero() && now.Add(res.RetryAfter).After(deadline)) {
+			w.leave(waiter)
+			l.lock.Unlock()
+			return RateLimitExceededErr
+		}
+		l.lock.Unlock()
+
+		timer := time.NewTimer(res.RetryAfter)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			l.leave(w, waiter)
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *RateLimitRequester) leave(w *rateLimitWindow, waiter *rateLimitWaiter) {
+	l.lock.Lock()
+	w.leave(waiter)
+	l.lock.Unlock()
 }
 
-// Delete forwards the request to the internal Requester if it is
-// within the rate limit.
-func (l *RateLimitRequester) Delete(url string, p ParameterMap) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.Delete(url, p)
-	} else {
-		return nil, RateLimitExceededErr
+// Removes the waiter from the queue and wakes up the next one if the waiter
+// was at the front. Must be called with the lock held.
+func (w *rateLimitWindow) leave(waiter *rateLimitWaiter) {
+	for i, queued := range w.waiters {
+		if queued != waiter {
+			continue
+		}
+
+		w.waiters = append(w.waiters[:i], w.waiters[i+1:]...)
+		if i == 0 && len(w.waiters) > 0 {
+			close(w.waiters[0].ready)
+		}
+		return
 	}
 }
 
-func (l *RateLimitRequester) makeRequest(url string, p ParameterMap, method string, load *payload) (r Response, err error) {
-	if l.allowed() {
-		return l.requester.makeRequest(url, p, method, load)
-	} else {
-		return nil, RateLimitExceededErr
+func (l *RateLimitRequester) makeRequest(ctx context.Context, url string, p ParameterMap, method string, load *payload, opts ...RequestOption) (r Response, err error) {
+	if err = l.allowedContext(ctx, url); err != nil {
+		return nil, err
 	}
+
+	return l.requester.makeRequest(ctx, url, p, method, load, opts...)
 }
@@ -1,9 +1,13 @@
 package walgo
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -452,3 +456,450 @@ func TestLimitedPutJson(t *testing.T) {
 		}
 	}
 }
+
+func TestLimitedCancelledContext(t *testing.T) {
+	requester := NewRateLimitRequester(defaultRequester, 1, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := requester.GetContext(ctx, "http://example.com", nil)
+	if err != context.Canceled {
+		t.Fatal("Cancelled context should be reported:", err)
+	}
+
+	if !requester.(*RateLimitRequester).allowed("") {
+		t.Fatal("Cancelled request should not use up the limit.")
+	}
+}
+
+func TestLimitedPatch(t *testing.T) {
+	server := newMethodServer()
+	defer server.Close()
+
+	requester := NewRateLimitRequester(defaultRequester, 1, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		res, err := requester.PatchRaw(server.URL, nil, []byte("data"))
+		if i < 1 {
+			if err != nil || res.Error() != nil {
+				t.Fatal(err)
+			}
+		} else {
+			if err != RateLimitExceededErr {
+				t.Fatal("Allowed to request beyond rate limit:", i, err)
+			}
+		}
+	}
+}
+
+func TestBlockingLimitedGet(t *testing.T) {
+	server := newMethodServer()
+	defer server.Close()
+
+	requester := NewBlockingRateLimitRequester(defaultRequester, 2, 100*time.Millisecond, time.Second)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		res, err := requester.Get(server.URL, nil)
+		if err != nil || res.Error() != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatal("Third request should wait for a free slot:", elapsed)
+	}
+}
+
+func TestBlockingLimitedMaxWait(t *testing.T) {
+	// A request waiting for the max wait before failing would time out the
+	// test.
+	requester := NewBlockingRateLimitRequester(defaultRequester, 1, time.Hour, 30*time.Minute)
+
+	if !requester.(*RateLimitRequester).allowed("") {
+		t.Fatal("First request should be allowed.")
+	}
+
+	_, err := requester.Get("http://example.com", nil)
+	if err != RateLimitExceededErr {
+		t.Fatal("Request that can't be served in time should fail fast:", err)
+	}
+}
+
+func TestBlockingLimitedContext(t *testing.T) {
+	requester := NewBlockingRateLimitRequester(defaultRequester, 1, 200*time.Millisecond, 0)
+	l := requester.(*RateLimitRequester)
+
+	if !l.allowed("") {
+		t.Fatal("First request should be allowed.")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := requester.GetContext(ctx, "http://example.com", nil)
+	if err != RateLimitExceededErr {
+		t.Fatal("Request beyond the context deadline should fail:", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	err = l.wait(ctx, "")
+	if err != context.Canceled {
+		t.Fatal("Cancelled context should be reported:", err)
+	}
+
+	if len(l.windows[""].waiters) != 0 {
+		t.Fatal("Cancelled waiter should leave the queue.")
+	}
+}
+
+func TestBlockingLimitedFairness(t *testing.T) {
+	requester := NewBlockingRateLimitRequester(defaultRequester, 1, 50*time.Millisecond, 0)
+	l := requester.(*RateLimitRequester)
+
+	if !l.allowed("") {
+		t.Fatal("First request should be allowed.")
+	}
+
+	var order []int
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := l.wait(context.Background(), ""); err != nil {
+				t.Error(err)
+				return
+			}
+			lock.Lock()
+			order = append(order, i)
+			lock.Unlock()
+		}(i)
+
+		for {
+			l.lock.Lock()
+			queued := len(l.windows[""].waiters)
+			l.lock.Unlock()
+			if queued == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	wg.Wait()
+
+	for i, n := range order {
+		if i != n {
+			t.Fatal("Waiters should be served in arrival order:", order)
+		}
+	}
+}
+
+func TestKeyedLimitedGet(t *testing.T) {
+	serverA := newMethodServer()
+	defer serverA.Close()
+	serverB := newMethodServer()
+	defer serverB.Close()
+
+	hostB, _ := url.Parse(serverB.URL)
+	requester := NewKeyedRateLimitRequester(defaultRequester, KeyedRateLimitConfig{
+		Default: RateLimitRule{Limit: 1, Duration: time.Hour},
+		Rules:   map[string]RateLimitRule{hostB.Host: {Limit: 2, Duration: time.Hour}},
+	})
+
+	for i, c := range []struct {
+		url     string
+		allowed bool
+	}{
+		{serverA.URL, true},
+		{serverB.URL, true},
+		{serverA.URL, false},
+		{serverB.URL, true},
+		{serverB.URL, false},
+	} {
+		_, err := requester.Get(c.url, nil)
+		if c.allowed && err != nil {
+			t.Fatal("Request should be allowed:", i, err)
+		}
+		if !c.allowed && err != RateLimitExceededErr {
+			t.Fatal("Request should be limited:", i, err)
+		}
+	}
+}
+
+func TestHostPathKey(t *testing.T) {
+	key := HostPathKey("/api", "/api/v2", "/static")
+
+	for path, expected := range map[string]string{
+		"/api/v1/users": "example.com/api",
+		"/api/v2/users": "example.com/api/v2",
+		"/static/x.png": "example.com/static",
+		"/index.html":   "example.com",
+		"/api":          "example.com/api",
+		"/apiary":       "example.com",
+		"/api/v2x":      "example.com/api",
+	} {
+		u, _ := url.Parse("http://example.com" + path)
+		if k := key(u); k != expected {
+			t.Fatalf("Unexpected key for %s: %s", path, k)
+		}
+	}
+}
+
+func TestKeyedLimitEviction(t *testing.T) {
+	requester := NewKeyedRateLimitRequester(defaultRequester, KeyedRateLimitConfig{
+		Default:     RateLimitRule{Limit: 1, Duration: 10 * time.Millisecond},
+		IdleTimeout: 20 * time.Millisecond,
+	})
+	l := requester.(*RateLimitRequester)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for _, key := range []string{"a", "b", "c"} {
+		if !l.allowed(key) {
+			t.Fatal("First request should be allowed:", key)
+		}
+	}
+
+	now = now.Add(30 * time.Millisecond)
+
+	if !l.allowed("d") {
+		t.Fatal("First request should be allowed.")
+	}
+
+	if len(l.windows) != 1 {
+		t.Fatal("Idle keys should be evicted:", len(l.windows))
+	}
+}
+
+func newClientRequest(client string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.RemoteAddr = client + ":12345"
+	return req
+}
+
+func TestRateLimitCleanup(t *testing.T) {
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm:       SlidingLog{Limit: 1, Duration: 5 * time.Millisecond},
+		CleanupInterval: 10 * time.Millisecond,
+	}, IPRatePolicy{}, &hitCountHandler{})
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	for _, client := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"} {
+		r.ServeHTTP(httptest.NewRecorder(), newClientRequest(client))
+	}
+
+	now = now.Add(20 * time.Millisecond)
+	r.ServeHTTP(httptest.NewRecorder(), newClientRequest("127.0.0.4"))
+
+	if len(r.limiters) != 1 {
+		t.Fatal("Idle clients should be forgotten:", len(r.limiters))
+	}
+}
+
+func TestRateLimitMaxClients(t *testing.T) {
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm:  SlidingLog{Limit: 1, Duration: time.Hour},
+		MaxClients: 2,
+	}, IPRatePolicy{}, &hitCountHandler{})
+
+	for _, client := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.1", "127.0.0.3"} {
+		r.ServeHTTP(httptest.NewRecorder(), newClientRequest(client))
+	}
+
+	if len(r.limiters) != 2 || r.order.Len() != 2 {
+		t.Fatal("Unexpected number of clients:", len(r.limiters), r.order.Len())
+	}
+
+	if _, ok := r.limiters["127.0.0.2"]; ok {
+		t.Fatal("Least recently seen client should be forgotten.")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newClientRequest("127.0.0.1"))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatal("Recently seen client should still be limited:", w.Code)
+	}
+}
+
+func TestRateLimitJanitor(t *testing.T) {
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm:       SlidingLog{Limit: 1, Duration: 5 * time.Millisecond},
+		CleanupInterval: 10 * time.Millisecond,
+		Janitor:         true,
+	}, IPRatePolicy{}, &hitCountHandler{})
+	defer r.Stop()
+
+	// The janitor reads the clock with the lock held.
+	now := time.Now()
+	r.lock.Lock()
+	r.now = func() time.Time { return now }
+	r.lock.Unlock()
+
+	for _, client := range []string{"127.0.0.1", "127.0.0.2"} {
+		r.ServeHTTP(httptest.NewRecorder(), newClientRequest(client))
+	}
+
+	r.lock.Lock()
+	now = now.Add(time.Minute)
+	r.lock.Unlock()
+
+	// Waits for the next runs of the janitor.
+	clients := -1
+	for i := 0; i < 100 && clients != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+
+		r.lock.Lock()
+		clients = len(r.limiters)
+		r.lock.Unlock()
+	}
+
+	if clients != 0 {
+		t.Fatal("Janitor should forget idle clients:", clients)
+	}
+
+	r.Stop()
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm: SlidingLog{Limit: 2, Duration: time.Minute},
+		Headers:   XRateLimitHeaders,
+	}, IPRatePolicy{}, &hitCountHandler{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newClientRequest("127.0.0.1"))
+
+	if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatal("Unexpected headers:", w.Header())
+	}
+
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil || reset < time.Now().Add(59*time.Second).Unix() || reset > time.Now().Add(61*time.Second).Unix() {
+		t.Fatal("Unexpected reset:", w.Header().Get("X-RateLimit-Reset"))
+	}
+
+	if w.Header().Get("Retry-After") != "" || w.Header().Get("RateLimit") != "" {
+		t.Fatal("Unexpected headers:", w.Header())
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), newClientRequest("127.0.0.1"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newClientRequest("127.0.0.1"))
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatal("Unexpected rejection:", w.Code, w.Header())
+	}
+}
+
+func TestNoRateLimitHeaders(t *testing.T) {
+	r := NewRateLimiter(1, time.Minute, IPRatePolicy{}, &hitCountHandler{})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newClientRequest("127.0.0.1"))
+
+		for _, name := range []string{"Retry-After", "X-RateLimit-Limit", "RateLimit", "RateLimit-Policy"} {
+			if w.Header().Get(name) != "" {
+				t.Fatal("No headers should be set by default:", w.Header())
+			}
+		}
+	}
+}
+
+func TestIETFRateLimitHeaders(t *testing.T) {
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm: FixedWindow{Limit: 5, Duration: time.Hour},
+		Headers:   IETFRateLimitHeaders,
+	}, IPRatePolicy{}, nil)
+
+	hitCount := 0
+	f := r.LimitHandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hitCount++
+	})
+
+	w := httptest.NewRecorder()
+	f(w, newClientRequest("127.0.0.1"))
+
+	if w.Header().Get("RateLimit-Policy") != `"default";q=5;w=3600` || !strings.HasPrefix(w.Header().Get("RateLimit"), `"default";r=4;t=`) {
+		t.Fatal("Unexpected headers:", w.Header())
+	}
+
+	if w.Header().Get("X-RateLimit-Limit") != "" || hitCount != 1 {
+		t.Fatal("Unexpected response:", w.Header(), hitCount)
+	}
+
+	status, ok := ParseRateLimitHeaders(w.Header(), time.Now())
+	if !ok || status.Limit != 5 || status.Remaining != 4 || status.Window != time.Hour {
+		t.Fatal("Headers should be readable by ParseRateLimitHeaders:", status)
+	}
+}
+
+func TestRateLimitRejectHandlers(t *testing.T) {
+	var limitedErr error
+	var limitedRetry time.Duration
+
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm: SlidingLog{Limit: 1, Duration: time.Minute},
+		OnLimited: func(w http.ResponseWriter, req *http.Request, err error, retryAfter time.Duration) {
+			limitedErr, limitedRetry = err, retryAfter
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+		OnPolicyError: StatusRejectHandler(http.StatusUnauthorized),
+	}, TokenRatePolicy{}, &hitCountHandler{})
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatal("Missing token should be unauthorized:", w.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		req = httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		req.Header.Set(authorizationHeader, bearerPrefix+"token")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+	}
+
+	if w.Code != http.StatusServiceUnavailable || limitedErr != RateLimitExceededErr {
+		t.Fatal("Limited handler should be used:", w.Code, limitedErr)
+	}
+
+	if limitedRetry <= 59*time.Second || limitedRetry > time.Minute {
+		t.Fatal("Unexpected retry time:", limitedRetry)
+	}
+}
+
+func TestRateLimitFailOpen(t *testing.T) {
+	h := &hitCountHandler{}
+	r := NewRateLimiterWithConfig(RateLimiterConfig{
+		Algorithm: SlidingLog{Limit: 1, Duration: time.Minute},
+		FailOpen:  true,
+	}, CookieRatePolicy{Name: "session"}, h)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com", nil))
+		if w.Code != http.StatusOK {
+			t.Fatal("Unresolvable clients should be let through:", w.Code)
+		}
+	}
+
+	if h.hitCount != 3 {
+		t.Fatal("Wrong hit count:", h.hitCount)
+	}
+}
@@ -2,7 +2,9 @@ package walgo
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -24,78 +26,294 @@ func init() {
 	defaultRequester = NewRequester(http.DefaultClient, DefaultClientName, "")
 }
 
+// Requester performs HTTP requests. Every method accepts a list of
+// RequestOptions used to customise the outgoing request.
 type Requester interface {
 	// Get performs a GET reuqest to the given URL with the given parameters.
-	Get(url string, p ParameterMap) (res Response, err error)
+	Get(url string, p ParameterMap, opts ...RequestOption) (res Response, err error)
+
+	// GetContext is like Get but uses the given context for the request.
+	// Cancelling the context aborts the request.
+	GetContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (res Response, err error)
 
 	// Post performs a POST request to the given URL with the given
 	// parameters and no request body.
-	Post(url string, p ParameterMap) (r Response, err error)
+	Post(url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// PostContext is like Post but uses the given context for the request.
+	// Cancelling the context aborts the request.
+	PostContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
 
 	// PostJson performs a POST request to the given URL with the given
 	// parameters and the supplied interface type encoded as JSON.
-	PostJson(url string, p ParameterMap, v interface{}) (r Response, err error)
+	// The Content-Type header is application/json unless it is set with
+	// WithHeader.
+	PostJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error)
+
+	// PostJsonContext is like PostJson but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	PostJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error)
 
 	// PostRaw performs a POST request to the given URL with the given
 	// parameters and the supplied bytes as the request body.
-	PostRaw(url string, p ParameterMap, data []byte) (r Response, err error)
+	PostRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error)
+
+	// PostRawContext is like PostRaw but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	PostRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error)
 
 	// PostMultipart performs a POST request to the given URL with the given
 	// parameters and the supplied multipart payload encoded as the request
 	// body.
-	PostMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error)
+	PostMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error)
+
+	// PostMultipartContext is like PostMultipart but uses the given context
+	// for the request. Cancelling the context aborts the request.
+	PostMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error)
 
 	// PostValues performs a POST request to the given URL with the given
 	// parameters and a body consisting of the supplied values urlencoded.
-	PostValues(url string, p ParameterMap, v url.Values) (r Response, err error)
+	PostValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error)
+
+	// PostValuesContext is like PostValues but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	PostValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error)
+
+	// PostReader performs a POST request to the given URL with the given
+	// parameters and a body streamed from the supplied reader. The length
+	// is the number of bytes the reader provides - or -1 if it is unknown.
+	// If the reader is an io.ReadCloser it is closed when the request is
+	// done, even if the body was never sent.
+	PostReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error)
+
+	// PostReaderContext is like PostReader but uses the given context for
+	// the request. Cancelling the context aborts the request.
+	PostReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error)
 
 	// Put performs a PUT request to the given URL with the given parameters.
-	Put(url string, p ParameterMap) (r Response, err error)
+	Put(url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// PutContext is like Put but uses the given context for the request.
+	// Cancelling the context aborts the request.
+	PutContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
 
 	// PutJson performs a PUT request to the given URL with the given
 	// parameters and the supplied interface type encoded as JSON.
-	PutJson(url string, p ParameterMap, v interface{}) (r Response, err error)
+	// The Content-Type header is application/json unless it is set with
+	// WithHeader.
+	PutJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error)
+
+	// PutJsonContext is like PutJson but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	PutJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error)
 
 	// PutRaw performs a PUT request to the given URL with the given
 	// parameters and the supplied bytes as the request body.
-	PutRaw(url string, p ParameterMap, data []byte) (r Response, err error)
+	PutRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error)
+
+	// PutRawContext is like PutRaw but uses the given context for the request.
+	// Cancelling the context aborts the request.
+	PutRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error)
 
 	// PutMultipart performs a PUT request to the given URL with the given
 	// parameters and the supplied multipart payload encoded as the request
 	// body.
-	PutMultipart(url string, p ParameterMap, m *MultipartPayload) (r Response, err error)
+	PutMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error)
+
+	// PutMultipartContext is like PutMultipart but uses the given context for
+	// the request. Cancelling the context aborts the request.
+	PutMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error)
 
 	// PutValues performs a PUT request to the given URL with the given
 	// parameters and a body consisting of the supplied values urlencoded.
-	PutValues(url string, p ParameterMap, v url.Values) (r Response, err error)
+	PutValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error)
+
+	// PutValuesContext is like PutValues but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	PutValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error)
+
+	// PutReader performs a PUT request to the given URL with the given
+	// parameters and a body streamed from the supplied reader. The length
+	// is the number of bytes the reader provides - or -1 if it is unknown.
+	// If the reader is an io.ReadCloser it is closed when the request is
+	// done, even if the body was never sent.
+	PutReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error)
+
+	// PutReaderContext is like PutReader but uses the given context for
+	// the request. Cancelling the context aborts the request.
+	PutReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error)
 
 	// Delete peforms a DELETE request to the given URL with the given
 	// parameters.
-	Delete(url string, p ParameterMap) (r Response, err error)
+	Delete(url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// DeleteContext is like Delete but uses the given context for the request.
+	// Cancelling the context aborts the request.
+	DeleteContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// Patch performs a PATCH request to the given URL with the given
+	// parameters and no request body.
+	Patch(url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
 
-	makeRequest(url string, p ParameterMap, method string, l *payload) (r Response, err error)
+	// PatchContext is like Patch but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	PatchContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// PatchJson performs a PATCH request to the given URL with the given
+	// parameters and the supplied interface type encoded as JSON.
+	// The Content-Type header is application/json unless it is set with
+	// WithHeader.
+	PatchJson(url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error)
+
+	// PatchJsonContext is like PatchJson but uses the given context for
+	// the request. Cancelling the context aborts the request.
+	PatchJsonContext(ctx context.Context, url string, p ParameterMap, v interface{}, opts ...RequestOption) (r Response, err error)
+
+	// PatchRaw performs a PATCH request to the given URL with the given
+	// parameters and the supplied bytes as the request body.
+	PatchRaw(url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error)
+
+	// PatchRawContext is like PatchRaw but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	PatchRawContext(ctx context.Context, url string, p ParameterMap, data []byte, opts ...RequestOption) (r Response, err error)
+
+	// PatchMultipart performs a PATCH request to the given URL with the
+	// given parameters and the supplied multipart payload encoded as the
+	// request body.
+	PatchMultipart(url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error)
+
+	// PatchMultipartContext is like PatchMultipart but uses the given
+	// context for the request. Cancelling the context aborts the request.
+	PatchMultipartContext(ctx context.Context, url string, p ParameterMap, m *MultipartPayload, opts ...RequestOption) (r Response, err error)
+
+	// PatchValues performs a PATCH request to the given URL with the given
+	// parameters and a body consisting of the supplied values urlencoded.
+	PatchValues(url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error)
+
+	// PatchValuesContext is like PatchValues but uses the given context
+	// for the request. Cancelling the context aborts the request.
+	PatchValuesContext(ctx context.Context, url string, p ParameterMap, v url.Values, opts ...RequestOption) (r Response, err error)
+
+	// PatchReader performs a PATCH request to the given URL with the given
+	// parameters and a body streamed from the supplied reader. The length
+	// is the number of bytes the reader provides - or -1 if it is unknown.
+	// If the reader is an io.ReadCloser it is closed when the request is
+	// done, even if the body was never sent.
+	PatchReader(url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error)
+
+	// PatchReaderContext is like PatchReader but uses the given context
+	// for the request. Cancelling the context aborts the request.
+	PatchReaderContext(ctx context.Context, url string, p ParameterMap, body io.Reader, length int64, opts ...RequestOption) (r Response, err error)
+
+	// Head performs a HEAD request to the given URL with the given
+	// parameters. The response has no body.
+	Head(url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// HeadContext is like Head but uses the given context for the request.
+	// Cancelling the context aborts the request.
+	HeadContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// Options performs a OPTIONS request to the given URL with the given
+	// parameters.
+	Options(url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// OptionsContext is like Options but uses the given context for the
+	// request. Cancelling the context aborts the request.
+	OptionsContext(ctx context.Context, url string, p ParameterMap, opts ...RequestOption) (r Response, err error)
+
+	// Do performs a request using the given method to the given URL with the
+	// given parameters and body. The body may be nil for requests without a
+	// body.
+	Do(method, url string, p ParameterMap, body Body, opts ...RequestOption) (r Response, err error)
+
+	// DoContext is like Do but uses the given context for the request.
+	// Cancelling the context aborts the request.
+	DoContext(ctx context.Context, method, url string, p ParameterMap, body Body, opts ...RequestOption) (r Response, err error)
+
+	makeRequest(ctx context.Context, url string, p ParameterMap, method string, l *payload, opts ...RequestOption) (r Response, err error)
 }
 
 type requesterImpl struct {
+	requestMethods
+
 	client    *http.Client
 	userAgent string
 	authToken string
+	defaults  []RequestOption
 }
 
 // NewRequester creates a new Requester using the supplied client. Every
 // request has the User-Agent header set to the given value and if the
 // authentication token differs from "" it is also used as the Authorization
 // header value (with the "Bearer "-prefix).
-func NewRequester(c *http.Client, userAgent, authToken string) (r Requester) {
-	return &requesterImpl{
+//
+// The given options are applied to every request made by the requester.
+func NewRequester(c *http.Client, userAgent, authToken string, opts ...RequestOption) (r Requester) {
+	f := &requesterImpl{
 		client:    c,
 		userAgent: userAgent,
 		authToken: authToken,
+		defaults:  opts,
+	}
+	f.send = f.makeRequest
+	return f
+}
+
+func (f *requesterImpl) makeRequest(ctx context.Context, urlStr string, p ParameterMap, method string, l *payload, opts ...RequestOption) (r Response, err error) {
+	u, err := buildURL(urlStr, p)
+	if err != nil {
+		return nil, err
+	}
+
+	options := buildRequestOptions(f.defaults, opts)
+	startTime := time.Now()
+
+	res, req, err := f.roundTrip(ctx, u, method, l, options)
+	if err != nil {
+		return nil, err
 	}
+
+	if options.authenticator != nil && res.code == http.StatusUnauthorized {
+		// The authenticator learns from the challenge even if a streamed
+		// body can't be sent again, so the next request succeeds.
+		retry, err := options.authenticator.Challenge(req, res)
+		retry = retry && l.replayable()
+		if err != nil || retry {
+			res.Body().Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if retry {
+			res, _, err = f.roundTrip(ctx, u, method, l, options)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res.duration = time.Now().Sub(startTime)
+
+	if options.statusErrors && (res.code < 200 || res.code > 299) {
+		if res.body != nil {
+			res.data, _ = ioutil.ReadAll(io.LimitReader(res.body, statusErrorBodySize))
+			res.body.Close()
+		}
+
+		statusErr := newStatusError(method, u.String(), res)
+		res.err = statusErr
+		statusErr.Response = res
+		statusErr.decode = options.decodeError
+		return res, statusErr
+	}
+
+	return res, nil
 }
 
-func (f *requesterImpl) makeRequest(urlStr string, p ParameterMap, method string, l *payload) (r Response, err error) {
-	u, err := url.Parse(urlStr)
+// Parses the URL and adds the parameters to the query string.
+func buildURL(urlStr string, p ParameterMap) (u *url.URL, err error) {
+	u, err = url.Parse(urlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -109,24 +327,25 @@ func (f *requesterImpl) makeRequest(urlStr string, p ParameterMap, method string
 	}
 
 	u.RawQuery = query.Encode()
+	return u, nil
+}
 
-	startTime := time.Now()
-	code := -1
-	var output []byte
-
+// Builds and sends a single request to the given URL and reads the
+// response. The request is returned with the response.
+func (f *requesterImpl) roundTrip(ctx context.Context, u *url.URL, method string, l *payload, options *requestOptions) (res responseImpl, req *http.Request, err error) {
 	buffer := &bytes.Buffer{}
 
 	if l != nil {
 		data := l.getData()
 		c, err2 := buffer.Write(data)
 		if c != len(data) || err2 != nil {
-			return nil, errors.New("Error creating data buffer.")
+			return res, nil, errors.New("Error creating data buffer.")
 		}
 	}
 
-	req, err := http.NewRequest(method, u.String(), buffer)
+	req, err = http.NewRequestWithContext(ctx, method, u.String(), buffer)
 	if err != nil {
-		return nil, err
+		return res, nil, err
 	}
 
 	if l != nil {
@@ -138,25 +357,72 @@ func (f *requesterImpl) makeRequest(urlStr string, p ParameterMap, method string
 		req.Header.Add(authorizationHeader, bearerPrefix+f.authToken)
 	}
 
+	options.applyHeaders(req.Header)
+
+	if options.authenticator != nil {
+		if err = options.authenticator.Authenticate(req); err != nil {
+			return res, nil, err
+		}
+	}
+
+	if options.signer != nil {
+		body := []byte{}
+		if l != nil && l.open != nil {
+			body = nil
+		} else if l != nil && l.getData() != nil {
+			body = l.getData()
+		}
+
+		if err = options.signer.Sign(req, body); err != nil {
+			return res, nil, err
+		}
+	}
+
+	if l != nil && l.open != nil {
+		req.Body = l.openBody()
+		req.ContentLength = l.length
+
+		// GetBody still returns the empty buffer. Without it the client
+		// returns redirects needing the body instead of sending it empty.
+		req.GetBody = nil
+		if l.replay {
+			req.GetBody = func() (io.ReadCloser, error) {
+				return l.openBody(), nil
+			}
+		}
+	}
+
+	res.authenticated = req.Header.Get(authorizationHeader) != "" || options.authenticator != nil || options.signer != nil
+
+	if options.uploadProgress != nil && req.Body != nil && req.Body != http.NoBody {
+		req.Body = &progressReader{body: req.Body, total: req.ContentLength, progress: options.uploadProgress}
+	}
+
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return nil, err
+		return res, nil, err
 	}
-	if resp != nil && resp.Body != nil {
-		code = resp.StatusCode
-		output, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
+
+	res.code = -1
+	if resp.Body != nil {
+		res.code = resp.StatusCode
+		if options.stream {
+			res.body = newStreamBody(resp.Body)
+		} else {
+			defer resp.Body.Close()
+			res.data, err = readBody(resp.Body, options.maxBodySize)
+			if err != nil {
+				return res, nil, err
+			}
 		}
 	}
 
-	duration := time.Now().Sub(startTime)
-
-	r = responseImpl{
-		data:     output,
-		code:     code,
-		duration: duration,
+	res.header = resp.Header
+	res.trailer = resp.Trailer
+	res.proto = resp.Proto
+	if resp.Request != nil {
+		res.url = resp.Request.URL
 	}
 
-	return r, err
+	return res, req, nil
 }
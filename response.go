@@ -1,7 +1,12 @@
 package walgo
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -9,6 +14,11 @@ type Response interface {
 	// Data returns the raw bytes from the body of the response.
 	Data() (data []byte)
 
+	// Body returns a reader for the response body. For streamed responses
+	// (see Stream) this is the body as received from the server which the
+	// caller must close. Otherwise it reads the buffered data.
+	Body() (body io.ReadCloser)
+
 	// String returns the content of the response body as a string.
 	String() (s string)
 
@@ -25,6 +35,25 @@ type Response interface {
 
 	// Error gives the error that occured during the request - if any.
 	Error() (err error)
+
+	// Header returns the headers sent by the server.
+	Header() (h http.Header)
+
+	// Trailer returns the trailers sent by the server after the response
+	// body.
+	Trailer() (t http.Header)
+
+	// Proto returns the protocol used for the response, eg. "HTTP/1.1" or
+	// "HTTP/2.0".
+	Proto() (proto string)
+
+	// URL returns the URL of the final request made - if the client
+	// followed any redirects this differs from the requested URL.
+	URL() (u *url.URL)
+
+	// CacheStatus tells if the response was served from a cache - see
+	// CachingRequester.
+	CacheStatus() (status CacheStatus)
 }
 
 type responseImpl struct {
@@ -32,12 +61,29 @@ type responseImpl struct {
 	code     int
 	duration time.Duration
 	err      error
+	header   http.Header
+	trailer  http.Header
+	proto    string
+	url      *url.URL
+	body     io.ReadCloser
+
+	// Tells if the request carried credentials (see CachingRequester).
+	authenticated bool
+
+	cacheStatus CacheStatus
 }
 
 func (r responseImpl) Data() (data []byte) {
 	return r.data
 }
 
+func (r responseImpl) Body() (body io.ReadCloser) {
+	if r.body != nil {
+		return r.body
+	}
+	return ioutil.NopCloser(bytes.NewReader(r.data))
+}
+
 func (r responseImpl) String() (s string) {
 	return string(r.data)
 }
@@ -58,3 +104,23 @@ func (r responseImpl) JSON(v interface{}) (err error) {
 func (r responseImpl) Error() (err error) {
 	return r.err
 }
+
+func (r responseImpl) Header() (h http.Header) {
+	return r.header
+}
+
+func (r responseImpl) Trailer() (t http.Header) {
+	return r.trailer
+}
+
+func (r responseImpl) Proto() (proto string) {
+	return r.proto
+}
+
+func (r responseImpl) URL() (u *url.URL) {
+	return r.url
+}
+
+func (r responseImpl) CacheStatus() (status CacheStatus) {
+	return r.cacheStatus
+}
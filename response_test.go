@@ -0,0 +1,44 @@
+package walgo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseHeaders(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", "\"v1\"")
+		w.Header().Set("Trailer", "X-Checksum")
+		fmt.Fprint(w, "body")
+		w.Header().Set("X-Checksum", "abc")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := Get(server.URL+"/old", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Header().Get("ETag") != "\"v1\"" {
+		t.Fatal("Unexpected ETag:", res.Header().Get("ETag"))
+	}
+
+	if res.Trailer().Get("X-Checksum") != "abc" {
+		t.Fatal("Unexpected trailer:", res.Trailer().Get("X-Checksum"))
+	}
+
+	if res.Proto() != "HTTP/1.1" {
+		t.Fatal("Unexpected protocol:", res.Proto())
+	}
+
+	if res.URL() == nil || res.URL().Path != "/new" {
+		t.Fatal("Final URL should be the redirect target:", res.URL())
+	}
+}
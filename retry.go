@@ -0,0 +1,261 @@
+package walgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes when and how often the RetryRequester retries a
+// request. The zero value of a field means the corresponding value from
+// DefaultRetryPolicy is used, and MaxElapsed and Jitter are turned off by a
+// negative value. The conditions (RetryConnectionErrors, RetryServerErrors,
+// RetryTooManyRequests and RetryCodes) are only taken from
+// DefaultRetryPolicy if none of them are set.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first.
+	MaxAttempts int
+
+	// MaxElapsed is the maximum time spent on a request including all the
+	// attempts and the waiting between them. No retry is scheduled if it
+	// would end after MaxElapsed. If it is negative there is no limit.
+	MaxElapsed time.Duration
+
+	// InitialBackoff is the time waited before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the time waited between two attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff grows with after each attempt.
+	Multiplier float64
+
+	// Jitter is the fraction (between 0 and 1) of the backoff that is
+	// randomised. 1 means "full jitter". If it is negative there is no
+	// jitter.
+	Jitter float64
+
+	// RetryConnectionErrors retries when the request fails before a
+	// response is received (eg. connection refused or reset).
+	RetryConnectionErrors bool
+
+	// RetryServerErrors retries on 5xx responses.
+	RetryServerErrors bool
+
+	// RetryTooManyRequests retries on 429 responses.
+	RetryTooManyRequests bool
+
+	// RetryCodes lists additional response codes that are retried.
+	RetryCodes []int
+
+	// RetryNonIdempotent allows retrying POST, PATCH and other methods that
+	// are not idempotent. A single request can opt in using AllowRetry.
+	RetryNonIdempotent bool
+
+	// IgnoreRetryAfter disables waiting the time given by the Retry-After
+	// header when it is longer than the computed backoff.
+	IgnoreRetryAfter bool
+}
+
+// DefaultRetryPolicy is a policy retrying idempotent requests on connection
+// errors, 5xx and 429 responses up to 3 attempts.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:           3,
+	MaxElapsed:            time.Minute,
+	InitialBackoff:        100 * time.Millisecond,
+	MaxBackoff:            10 * time.Second,
+	Multiplier:            2,
+	Jitter:                0.5,
+	RetryConnectionErrors: true,
+	RetryServerErrors:     true,
+	RetryTooManyRequests:  true,
+}
+
+// RetryRequester is a Requester that retries failed requests on the
+// internal Requester according to a RetryPolicy. The payload of a request
+// is built once and replayed on every attempt - streamed payloads (readers
+// and multipart) can't be replayed and are never retried.
+type RetryRequester struct {
+	requestMethods
+
+	requester Requester
+	policy    RetryPolicy
+}
+
+// NewRetryRequester creates a new Requester that forwards requests to the
+// given Requester and retries them according to the given policy.
+func NewRetryRequester(r Requester, policy RetryPolicy) (rr Requester) {
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if policy.MaxElapsed == 0 {
+		policy.MaxElapsed = DefaultRetryPolicy.MaxElapsed
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+	if policy.Jitter == 0 {
+		policy.Jitter = DefaultRetryPolicy.Jitter
+	} else if policy.Jitter < 0 {
+		policy.Jitter = 0
+	}
+	if !policy.RetryConnectionErrors && !policy.RetryServerErrors && !policy.RetryTooManyRequests && len(policy.RetryCodes) == 0 {
+		policy.RetryConnectionErrors = DefaultRetryPolicy.RetryConnectionErrors
+		policy.RetryServerErrors = DefaultRetryPolicy.RetryServerErrors
+		policy.RetryTooManyRequests = DefaultRetryPolicy.RetryTooManyRequests
+		policy.RetryCodes = DefaultRetryPolicy.RetryCodes
+	}
+
+	l := &RetryRequester{
+		requester: r,
+		policy:    policy,
+	}
+	l.send = l.makeRequest
+	return l
+}
+
+// AllowRetry allows the RetryRequester to retry the request even if the
+// method is not idempotent.
+func AllowRetry() RequestOption {
+	return func(o *requestOptions) {
+		o.allowRetry = true
+	}
+}
+
+func (l *RetryRequester) makeRequest(ctx context.Context, url string, p ParameterMap, method string, load *payload, opts ...RequestOption) (r Response, err error) {
+	replayable := load.replayable() && (l.policy.RetryNonIdempotent || isIdempotent(method) ||
+		buildRequestOptions(nil, opts).allowRetry)
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		r, err = l.requester.makeRequest(ctx, url, p, method, load, opts...)
+
+		if !replayable || attempt >= l.policy.MaxAttempts || ctx.Err() != nil || !l.shouldRetry(r, err) {
+			return r, err
+		}
+
+		wait := l.backoff(attempt)
+		if r != nil && !l.policy.IgnoreRetryAfter {
+			if after, ok := retryAfter(r.Header(), time.Now()); ok && after > wait {
+				wait = after
+			}
+		}
+
+		if l.policy.MaxElapsed > 0 && time.Since(start)+wait > l.policy.MaxElapsed {
+			return r, err
+		}
+
+		if r != nil {
+			r.Body().Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Checks the outcome of an attempt against the retry conditions.
+func (l *RetryRequester) shouldRetry(r Response, err error) bool {
+	code, isStatus := statusCode(err)
+	if err != nil && !isStatus {
+		return l.policy.RetryConnectionErrors && isConnectionError(err)
+	}
+
+	if !isStatus {
+		code = r.Code()
+	}
+	if l.policy.RetryServerErrors && code >= 500 && code <= 599 {
+		return true
+	}
+
+	if l.policy.RetryTooManyRequests && code == http.StatusTooManyRequests {
+		return true
+	}
+
+	for _, c := range l.policy.RetryCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Computes the exponential backoff with jitter before the given retry.
+func (l *RetryRequester) backoff(attempt int) time.Duration {
+	d := float64(l.policy.InitialBackoff) * math.Pow(l.policy.Multiplier, float64(attempt-1))
+	if d > float64(l.policy.MaxBackoff) {
+		d = float64(l.policy.MaxBackoff)
+	}
+
+	d -= d * l.policy.Jitter * rand.Float64()
+	return time.Duration(d)
+}
+
+// Checks if the method is idempotent as defined in RFC 7231.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// Checks if the error happened while talking to the server - as opposed to
+// errors building the request or the context being done.
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Parses the Retry-After header which holds either a number of seconds or
+// a HTTP date.
+func retryAfter(h http.Header, now time.Time) (d time.Duration, ok bool) {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if t.Before(now) {
+			return 0, true
+		}
+		return t.Sub(now), true
+	}
+
+	return 0, false
+}
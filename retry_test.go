@@ -0,0 +1,159 @@
+package walgo
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	InitialBackoff:       time.Millisecond,
+	RetryServerErrors:    true,
+	RetryTooManyRequests: true,
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	server, hits := newFailingServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	r := NewRetryRequester(defaultRequester, testRetryPolicy)
+	res, err := r.Get(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Code() != http.StatusOK || *hits != 3 {
+		t.Fatal("Request should succeed on third attempt:", res.Code(), *hits)
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	server, hits := newFailingServer(5, http.StatusInternalServerError)
+	defer server.Close()
+
+	r := NewRetryRequester(defaultRequester, testRetryPolicy)
+	res, err := r.Get(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Code() != http.StatusInternalServerError || *hits != 3 {
+		t.Fatal("Last response should be returned after 3 attempts:", res.Code(), *hits)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	server, hits := newFailingServer(1, http.StatusBadGateway)
+	defer server.Close()
+
+	r := NewRetryRequester(defaultRequester, testRetryPolicy)
+	res, err := r.PostRaw(server.URL, nil, []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Code() != http.StatusBadGateway || *hits != 1 {
+		t.Fatal("POST should not be retried:", res.Code(), *hits)
+	}
+
+	res, err = r.PostRaw(server.URL, nil, []byte("data"), AllowRetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Code() != http.StatusOK {
+		t.Fatal("POST allowed to retry should succeed:", res.Code())
+	}
+}
+
+func TestRetryConnectionError(t *testing.T) {
+	server, _ := newFailingServer(0, http.StatusOK)
+	url := server.URL
+	server.Close()
+
+	policy := testRetryPolicy
+	policy.RetryConnectionErrors = true
+
+	attempts := 0
+	r := NewRetryRequester(NewRequester(&http.Client{Transport: countingTransport{&attempts}}, "", ""), policy)
+	_, err := r.Get(url, nil)
+	if err == nil {
+		t.Fatal("Request to closed server should fail.")
+	}
+
+	if attempts != 3 {
+		t.Fatal("Connection errors should be retried:", attempts)
+	}
+}
+
+func TestRetryContextCancelled(t *testing.T) {
+	server, _ := newFailingServer(5, http.StatusTooManyRequests)
+	defer server.Close()
+
+	policy := testRetryPolicy
+	policy.InitialBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r := NewRetryRequester(defaultRequester, policy)
+	_, err := r.GetContext(ctx, server.URL, nil)
+	if err != context.DeadlineExceeded {
+		t.Fatal("Waiting for a retry should stop with the context:", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now()
+
+	h := http.Header{}
+	h.Set("Retry-After", "120")
+	if d, ok := retryAfter(h, now); !ok || d != 2*time.Minute {
+		t.Fatal("Unexpected Retry-After in seconds:", d, ok)
+	}
+
+	h.Set("Retry-After", now.Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d, ok := retryAfter(h, now); !ok || d <= 58*time.Second || d > time.Minute {
+		t.Fatal("Unexpected Retry-After as date:", d, ok)
+	}
+
+	h.Set("Retry-After", "soon")
+	if _, ok := retryAfter(h, now); ok {
+		t.Fatal("Invalid Retry-After should be ignored.")
+	}
+}
+
+type countingTransport struct {
+	count *int
+}
+
+func (c countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	*c.count++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestRetryJitter(t *testing.T) {
+	r := NewRetryRequester(defaultRequester, RetryPolicy{InitialBackoff: time.Second}).(*RetryRequester)
+	if r.policy.Jitter != DefaultRetryPolicy.Jitter {
+		t.Fatal("Zero jitter should use the default:", r.policy.Jitter)
+	}
+
+	r = NewRetryRequester(defaultRequester, RetryPolicy{InitialBackoff: time.Second, Jitter: -1}).(*RetryRequester)
+	if d := r.backoff(1); d != time.Second {
+		t.Fatal("Negative jitter should disable jitter:", d)
+	}
+}
+
+func TestRetryDefaultConditions(t *testing.T) {
+	r := NewRetryRequester(defaultRequester, RetryPolicy{MaxAttempts: 5}).(*RetryRequester)
+	if !r.policy.RetryConnectionErrors || !r.policy.RetryServerErrors || !r.policy.RetryTooManyRequests {
+		t.Fatal("Policy without conditions should use the default conditions:", r.policy)
+	}
+
+	r = NewRetryRequester(defaultRequester, RetryPolicy{RetryCodes: []int{http.StatusConflict}}).(*RetryRequester)
+	if r.policy.RetryConnectionErrors || r.policy.RetryServerErrors || r.policy.RetryTooManyRequests {
+		t.Fatal("Policy with conditions should keep them:", r.policy)
+	}
+}
@@ -0,0 +1,214 @@
+package walgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type cacheServer struct {
+	*httptest.Server
+	hits        int
+	notModified int
+}
+
+func newCacheServer() *cacheServer {
+	s := &cacheServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fresh", func(w http.ResponseWriter, r *http.Request) {
+		s.hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprintf(w, "fresh %d", s.hits)
+	})
+	mux.HandleFunc("/etag", func(w http.ResponseWriter, r *http.Request) {
+		s.hits++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, "etag body")
+	})
+	mux.HandleFunc("/nostore", func(w http.ResponseWriter, r *http.Request) {
+		s.hits++
+		w.Header().Set("Cache-Control", "no-store")
+	})
+	mux.HandleFunc("/vary", func(w http.ResponseWriter, r *http.Request) {
+		s.hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept")
+		fmt.Fprint(w, r.Header.Get("Accept"))
+	})
+	mux.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request) {
+		s.hits++
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		fmt.Fprintf(w, "public %d", s.hits)
+	})
+	mux.HandleFunc("/stale", func(w http.ResponseWriter, r *http.Request) {
+		s.hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Age", "120")
+		w.Header().Set("ETag", `"stale"`)
+		fmt.Fprintf(w, "stale %d", s.hits)
+	})
+	mux.HandleFunc("/expires", func(w http.ResponseWriter, r *http.Request) {
+		s.hits++
+		now := time.Now()
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+		w.Header().Set("Expires", now.Add(-time.Minute).UTC().Format(http.TimeFormat))
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func newJSONServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != jsonContentType {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		if r.Method == http.MethodPost {
+			if r.Header.Get(contentTypeHeader) != jsonContentType {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			var u testUser
+			json.NewDecoder(r.Body).Decode(&u)
+			u.Age++
+			json.NewEncoder(w).Encode(u)
+			return
+		}
+		w.Write([]byte(`{"name":"Alice","age":30}`))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"title":"No such user"}`))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func newMethodServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		if r.Method != http.MethodHead {
+			data, _ := ioutil.ReadAll(r.Body)
+			w.Write(data)
+		}
+	}))
+}
+
+type tokenServer struct {
+	*httptest.Server
+	fetches   int32
+	expiresIn int
+	grants    []string
+	lock      sync.Mutex
+}
+
+func newTokenServer(expiresIn int) *tokenServer {
+	s := &tokenServer{expiresIn: expiresIn}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&s.fetches, 1)
+		r.ParseForm()
+
+		s.lock.Lock()
+		s.grants = append(s.grants, r.Form.Get("grant_type"))
+		s.lock.Unlock()
+
+		user, pass, ok := r.BasicAuth()
+		if !ok {
+			user, pass = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+
+		if user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintf(w, `{"access_token":"token%d","token_type":"bearer","expires_in":%d,"refresh_token":"refresh%d"}`, n, s.expiresIn, n)
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token%d", atomic.LoadInt32(&s.fetches)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func newHeaderServer(headers *http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*headers = r.Header.Clone()
+	}))
+}
+
+type uploadInfo struct {
+	length   int64
+	encoding []string
+	body     []byte
+	form     map[string]string
+}
+
+func newUploadServer(info *uploadInfo) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info.length = r.ContentLength
+		info.encoding = r.TransferEncoding
+		if r.ParseMultipartForm(1024) == nil {
+			info.form = map[string]string{}
+			for k, v := range r.MultipartForm.Value {
+				info.form[k] = v[0]
+			}
+			for k, v := range r.MultipartForm.File {
+				f, _ := v[0].Open()
+				data, _ := ioutil.ReadAll(f)
+				info.form[k] = v[0].Filename + ":" + string(data)
+			}
+			return
+		}
+		info.body, _ = ioutil.ReadAll(r.Body)
+	}))
+}
+
+func newFailingServer(failures int, code int) (*httptest.Server, *int) {
+	hits := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits <= failures {
+			w.WriteHeader(code)
+			return
+		}
+		w.Write([]byte("ok"))
+	})), &hits
+}
+
+func newStreamServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write([]byte(body))
+	}))
+}
+
+func newThrottlingServer(count *int, headers map[string]string, code int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*count++
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(code)
+	}))
+}
@@ -0,0 +1,255 @@
+package walgo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Used instead of the body hash when the body is streamed.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+)
+
+// Signer signs outgoing requests by adding signature headers. It is given
+// to a Requester using the WithSigner option and is called as the last
+// step before the request is sent.
+type Signer interface {
+	// Sign adds the signature to the request. The body is nil if it is
+	// streamed and can't be part of the signature - otherwise it holds the
+	// request body (which may be empty).
+	Sign(req *http.Request, body []byte) (err error)
+}
+
+// WithSigner makes the request signed using the given Signer.
+func WithSigner(s Signer) RequestOption {
+	return func(o *requestOptions) {
+		o.signer = s
+	}
+}
+
+// HMACSigner signs requests using HMAC-SHA256 over the method, the path,
+// the sorted query string, the hex encoded SHA-256 hash of the body and a
+// timestamp - each on a line of their own. The timestamp (in Unix seconds)
+// and the hex encoded signature are sent in headers.
+type HMACSigner struct {
+	// Secret is the key used for the signature.
+	Secret []byte
+
+	// KeyID identifies the key. If it is not "" it is sent in the
+	// KeyIDHeader.
+	KeyID string
+
+	// The header names. If they are "" the names "X-Signature",
+	// "X-Timestamp" and "X-Key-Id" are used.
+	SignatureHeader string
+	TimestampHeader string
+	KeyIDHeader     string
+
+	// Now returns the current time. If it is nil time.Now is used.
+	Now func() time.Time
+}
+
+// Sign adds the timestamp and signature headers.
+func (s HMACSigner) Sign(req *http.Request, body []byte) (err error) {
+	timestamp := strconv.FormatInt(signingTime(s.Now).Unix(), 10)
+
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(HMACStringToSign(req, body, timestamp)))
+
+	req.Header.Set(headerName(s.TimestampHeader, "X-Timestamp"), timestamp)
+	req.Header.Set(headerName(s.SignatureHeader, "X-Signature"), hex.EncodeToString(mac.Sum(nil)))
+	if s.KeyID != "" {
+		req.Header.Set(headerName(s.KeyIDHeader, "X-Key-Id"), s.KeyID)
+	}
+
+	return nil
+}
+
+// HMACStringToSign returns the string signed by the HMACSigner. It can be
+// used by servers verifying the signature.
+func HMACStringToSign(req *http.Request, body []byte, timestamp string) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		payloadHash(body),
+		timestamp,
+	}, "\n")
+}
+
+// SigV4Signer signs requests using AWS Signature Version 4 - as used by AWS
+// and S3 compatible storage. The host, content type and all X-Amz-* headers
+// are signed.
+type SigV4Signer struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Service      string
+
+	// Now returns the current time. If it is nil time.Now is used.
+	Now func() time.Time
+}
+
+// Sign adds the X-Amz-Date and Authorization headers - and for S3 the
+// X-Amz-Content-Sha256 header.
+func (s SigV4Signer) Sign(req *http.Request, body []byte) (err error) {
+	now := signingTime(s.Now).UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	hash := payloadHash(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", hash)
+	}
+
+	canonical, signedHeaders := s.canonicalRequest(req, hash)
+
+	scope := strings.Join([]string{now.Format(sigV4DateFormat), s.Region, s.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, sha256Hex([]byte(canonical))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), now.Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set(authorizationHeader, sigV4Algorithm+" Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return nil
+}
+
+// Builds the canonical request and the list of signed headers.
+func (s SigV4Signer) canonicalRequest(req *http.Request, hash string) (canonical, signedHeaders string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			values := make([]string, len(v))
+			for i := range v {
+				values[i] = strings.Join(strings.Fields(v[i]), " ")
+			}
+			headers[name] = strings.Join(values, ",")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	path := req.URL.EscapedPath()
+	if s.Service == "s3" {
+		path = sigV4Escape(req.URL.Path, false)
+	} else {
+		path = sigV4Escape(path, false)
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	signedHeaders = strings.Join(names, ";")
+	canonical = strings.Join([]string{
+		req.Method,
+		path,
+		sigV4Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hash,
+	}, "\n")
+
+	return canonical, signedHeaders
+}
+
+// Encodes the query with the keys and values sorted and encoded by the
+// SigV4 rules.
+func sigV4Query(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for k, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, sigV4Escape(k, true)+"="+sigV4Escape(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// URI encodes everything but the unreserved characters (RFC 3986). Slashes
+// are kept unless encodeSlash is set.
+func sigV4Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+// Returns the hex encoded SHA-256 hash of the body - or UNSIGNED-PAYLOAD
+// if the body is streamed.
+func payloadHash(body []byte) string {
+	if body == nil {
+		return unsignedPayload
+	}
+	return sha256Hex(body)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func signingTime(now func() time.Time) time.Time {
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
+
+func headerName(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,159 @@
+package walgo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sigV4TestTime() time.Time {
+	return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+}
+
+// Test vectors from the AWS Signature Version 4 test suite and the AWS
+// documentation example.
+var sigV4Tests = []struct {
+	name      string
+	method    string
+	url       string
+	headers   map[string]string
+	body      string
+	service   string
+	signed    string
+	signature string
+}{
+	{
+		name:      "get-vanilla",
+		method:    http.MethodGet,
+		url:       "https://example.amazonaws.com/",
+		service:   "service",
+		signed:    "host;x-amz-date",
+		signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+	},
+	{
+		name:      "get-vanilla-query-order-key-case",
+		method:    http.MethodGet,
+		url:       "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+		service:   "service",
+		signed:    "host;x-amz-date",
+		signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+	},
+	{
+		name:      "post-vanilla",
+		method:    http.MethodPost,
+		url:       "https://example.amazonaws.com/",
+		service:   "service",
+		signed:    "host;x-amz-date",
+		signature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+	},
+	{
+		name:      "post-x-www-form-urlencoded",
+		method:    http.MethodPost,
+		url:       "https://example.amazonaws.com/",
+		headers:   map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		body:      "Param1=value1",
+		service:   "service",
+		signed:    "content-type;host;x-amz-date",
+		signature: "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+	},
+	{
+		name:      "iam-list-users",
+		method:    http.MethodGet,
+		url:       "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+		headers:   map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+		service:   "iam",
+		signed:    "content-type;host;x-amz-date",
+		signature: "5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+	},
+}
+
+func TestSigV4Vectors(t *testing.T) {
+	for _, test := range sigV4Tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
+
+		signer := SigV4Signer{
+			AccessKey: "AKIDEXAMPLE",
+			SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			Region:    "us-east-1",
+			Service:   test.service,
+			Now:       sigV4TestTime,
+		}
+		if err = signer.Sign(req, []byte(test.body)); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/" + test.service +
+			"/aws4_request, SignedHeaders=" + test.signed + ", Signature=" + test.signature
+		if req.Header.Get("Authorization") != expected {
+			t.Errorf("%s: unexpected authorization:\n%s\nexpected:\n%s", test.name, req.Header.Get("Authorization"), expected)
+		}
+
+		if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+			t.Errorf("%s: unexpected date: %s", test.name, req.Header.Get("X-Amz-Date"))
+		}
+	}
+}
+
+func TestSigV4S3(t *testing.T) {
+	var headers http.Header
+	server := newHeaderServer(&headers)
+	defer server.Close()
+
+	signer := SigV4Signer{AccessKey: "key", SecretKey: "secret", SessionToken: "session", Region: "eu-west-1", Service: "s3"}
+
+	if _, err := PutRaw(server.URL+"/bucket/my file.txt", nil, []byte("content"), WithSigner(signer)); err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("X-Amz-Content-Sha256") != sha256Hex([]byte("content")) {
+		t.Fatal("Unexpected payload hash:", headers.Get("X-Amz-Content-Sha256"))
+	}
+
+	if !strings.Contains(headers.Get("Authorization"), "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Fatal("Unexpected signed headers:", headers.Get("Authorization"))
+	}
+
+	if _, err := PutReader(server.URL+"/bucket/stream", nil, strings.NewReader("content"), 7, WithSigner(signer)); err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.Get("X-Amz-Content-Sha256") != unsignedPayload {
+		t.Fatal("Streamed body should be unsigned:", headers.Get("X-Amz-Content-Sha256"))
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	secret := []byte("secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(HMACStringToSign(r, body, r.Header.Get("X-Timestamp"))))
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) || r.Header.Get("X-Key-Id") != "partner" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	signer := HMACSigner{Secret: secret, KeyID: "partner"}
+	res, err := PostJson(server.URL+"/orders", ParameterMap{"b": "2", "a": "1"}, map[string]int{"id": 1}, WithSigner(signer))
+	if err != nil || res.Code() != http.StatusOK {
+		t.Fatal("Signature not accepted:", err, res.Code())
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/path?b=2&a=1", nil)
+	if s := HMACStringToSign(req, []byte{}, "1440938160"); s != "GET\n/path\na=1&b=2\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n1440938160" {
+		t.Fatal("Unexpected string to sign:", s)
+	}
+}
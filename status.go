@@ -0,0 +1,111 @@
+package walgo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// The maximum number of body bytes kept in a StatusError.
+const statusErrorBodySize = 512
+
+// StatusError is the error returned for responses with a non-2xx status
+// code when the request is made with the StatusErrors option. The response
+// is returned alongside the error and is also available as Response.
+type StatusError struct {
+	// Method is the method of the request.
+	Method string
+
+	// URL is the requested URL including the query string.
+	URL string
+
+	// Code is the status code of the response.
+	Code int
+
+	// Header holds the headers of the response.
+	Header http.Header
+
+	// Body holds the first bytes of the response body.
+	Body []byte
+
+	// Response is the full response.
+	Response Response
+
+	// Decodes the error body for the JSON helpers (see DecodeErrors).
+	decode func(statusErr *StatusError) error
+}
+
+// StatusErrors makes non-2xx responses return a *StatusError. It can be
+// given to NewRequester to enable it for every request.
+func StatusErrors() RequestOption {
+	return func(o *requestOptions) {
+		o.statusErrors = true
+	}
+}
+
+func newStatusError(method, url string, r Response) (err *StatusError) {
+	body := r.Data()
+	if len(body) > statusErrorBodySize {
+		body = body[:statusErrorBodySize]
+	}
+
+	return &StatusError{
+		Method:   method,
+		URL:      url,
+		Code:     r.Code(),
+		Header:   r.Header(),
+		Body:     body,
+		Response: r,
+	}
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.Code, http.StatusText(e.Code))
+	if len(e.Body) > 0 {
+		msg += ": " + string(e.Body)
+	}
+	return msg
+}
+
+// Gets the status code of err if it is a *StatusError.
+func statusCode(err error) (code int, ok bool) {
+	var e *StatusError
+	if errors.As(err, &e) {
+		return e.Code, true
+	}
+	return 0, false
+}
+
+// IsNotFound reports whether err is a *StatusError with the status code 404.
+func IsNotFound(err error) bool {
+	code, ok := statusCode(err)
+	return ok && code == http.StatusNotFound
+}
+
+// IsClientError reports whether err is a *StatusError with a 4xx status
+// code.
+func IsClientError(err error) bool {
+	code, ok := statusCode(err)
+	return ok && code >= 400 && code <= 499
+}
+
+// IsServerError reports whether err is a *StatusError with a 5xx status
+// code.
+func IsServerError(err error) bool {
+	code, ok := statusCode(err)
+	return ok && code >= 500 && code <= 599
+}
+
+// IsRetryable reports whether err is a *StatusError with a status code
+// indicating that the request can be retried later (408, 429, 500, 502,
+// 503 and 504).
+func IsRetryable(err error) bool {
+	code, _ := statusCode(err)
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
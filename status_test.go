@@ -0,0 +1,73 @@
+package walgo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, strings.Repeat("x", 1000), http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	res, err := Get(server.URL, nil)
+	if err != nil || res.Error() != nil {
+		t.Fatal("Status errors should be opt-in:", err)
+	}
+
+	res, err = Get(server.URL, ParameterMap{"id": "1"}, StatusErrors())
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatal("Expected a status error:", err)
+	}
+
+	if statusErr.Method != http.MethodGet || statusErr.URL != server.URL+"?id=1" || statusErr.Code != http.StatusNotFound {
+		t.Fatal("Unexpected status error:", statusErr.Method, statusErr.URL, statusErr.Code)
+	}
+
+	if len(statusErr.Body) != statusErrorBodySize {
+		t.Fatal("Body should be truncated:", len(statusErr.Body))
+	}
+
+	if statusErr.Header.Get("Content-Type") == "" {
+		t.Fatal("Headers should be kept.")
+	}
+
+	if res == nil || res.Error() != err || len(statusErr.Response.Data()) != 1001 {
+		t.Fatal("Response should be available.")
+	}
+
+	if !IsNotFound(err) || !IsClientError(err) || IsServerError(err) || IsRetryable(err) {
+		t.Fatal("Wrong classification of 404.")
+	}
+}
+
+func TestStatusErrorsDefault(t *testing.T) {
+	server, hits := newFailingServer(1, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	r := NewRetryRequester(NewRequester(http.DefaultClient, "", "", StatusErrors()), testRetryPolicy)
+	res, err := r.Get(server.URL, nil)
+	if err != nil {
+		t.Fatal("Retried request should succeed:", err)
+	}
+
+	if res.Code() != http.StatusOK || *hits != 2 {
+		t.Fatal("Status error should be retried:", res.Code(), *hits)
+	}
+}
+
+func TestStatusErrorClassification(t *testing.T) {
+	err := &StatusError{Code: http.StatusServiceUnavailable}
+	if !IsServerError(err) || !IsRetryable(err) || IsClientError(err) || IsNotFound(err) {
+		t.Fatal("Wrong classification of 503.")
+	}
+
+	if IsRetryable(errors.New("other")) || IsNotFound(nil) {
+		t.Fatal("Other errors should not be classified.")
+	}
+}
@@ -0,0 +1,171 @@
+package walgo
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+)
+
+var (
+	// BodyTooLargeErr is returned when a buffered response body is larger
+	// than the size given with MaxBodySize.
+	BodyTooLargeErr = errors.New("Response body too large.")
+)
+
+// ProgressFunc is called while transferring a body with the number of
+// bytes transferred so far and the total number of bytes - or -1 if the
+// total is unknown.
+type ProgressFunc func(transferred, total int64)
+
+// Stream makes the request return as soon as the response headers are
+// received. The body is not buffered and must be read using the Body
+// function of the Response - Data, String and JSON return nothing and
+// Duration is the time until the headers were received.
+//
+// The caller must close the body. It is closed automatically when it has
+// been read to the end and CopyTo and SaveToFile always close it.
+func Stream() RequestOption {
+	return func(o *requestOptions) {
+		o.stream = true
+	}
+}
+
+// MaxBodySize limits the size of buffered response bodies. Requests with a
+// larger body fail with BodyTooLargeErr. It has no effect on streamed
+// responses.
+func MaxBodySize(n int64) RequestOption {
+	return func(o *requestOptions) {
+		o.maxBodySize = n
+	}
+}
+
+// Reads the whole body while respecting the max size (if it is positive).
+func readBody(r io.Reader, max int64) (data []byte, err error) {
+	if max <= 0 {
+		return ioutil.ReadAll(r)
+	}
+
+	data, err = ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > max {
+		return nil, BodyTooLargeErr
+	}
+
+	return data, nil
+}
+
+// streamBody wraps a streamed response body. It closes the underlying body
+// when the end is reached and allows Close to be called more than once.
+type streamBody struct {
+	body io.ReadCloser
+	once sync.Once
+	err  error
+}
+
+func newStreamBody(body io.ReadCloser) *streamBody {
+	return &streamBody{body: body}
+}
+
+func (b *streamBody) Read(p []byte) (n int, err error) {
+	n, err = b.body.Read(p)
+	if err == io.EOF {
+		b.Close()
+	}
+	return n, err
+}
+
+func (b *streamBody) Close() error {
+	b.once.Do(func() {
+		b.err = b.body.Close()
+	})
+	return b.err
+}
+
+// UploadProgress makes the request report the progress while the request
+// body is sent. The total is -1 for streamed bodies of unknown length.
+func UploadProgress(progress ProgressFunc) RequestOption {
+	return func(o *requestOptions) {
+		o.uploadProgress = progress
+	}
+}
+
+// Wraps a request body reporting the progress after each read.
+type progressReader struct {
+	body     io.ReadCloser
+	read     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(data []byte) (n int, err error) {
+	n, err = p.body.Read(data)
+	if n > 0 {
+		p.read += int64(n)
+		p.progress(p.read, p.total)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	return p.body.Close()
+}
+
+// Wraps a writer reporting the progress after each write.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressWriter) Write(data []byte) (n int, err error) {
+	n, err = p.w.Write(data)
+	p.written += int64(n)
+	p.progress(p.written, p.total)
+	return n, err
+}
+
+// Gets the body length from the response headers - or -1 if unknown.
+func contentLength(r Response) int64 {
+	if n, err := strconv.ParseInt(r.Header().Get("Content-Length"), 10, 64); err == nil {
+		return n
+	}
+	return -1
+}
+
+// CopyTo copies the body of the response to the writer and closes it. If
+// progress is not nil it is called after every write.
+func CopyTo(w io.Writer, r Response, progress ProgressFunc) (n int64, err error) {
+	body := r.Body()
+	defer body.Close()
+
+	if progress != nil {
+		w = &progressWriter{w: w, total: contentLength(r), progress: progress}
+	}
+
+	return io.Copy(w, body)
+}
+
+// SaveToFile copies the body of the response to the file with the given
+// name and closes it. The file is created or truncated. If progress is not
+// nil it is called after every write.
+func SaveToFile(name string, r Response, progress ProgressFunc) (n int64, err error) {
+	f, err := os.Create(name)
+	if err != nil {
+		r.Body().Close()
+		return 0, err
+	}
+
+	n, err = CopyTo(f, r, progress)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return n, err
+}
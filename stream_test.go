@@ -0,0 +1,97 @@
+package walgo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	body := strings.Repeat("walgo", 10000)
+	server := newStreamServer(body)
+	defer server.Close()
+
+	res, err := Get(server.URL, nil, Stream())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Data()) != 0 {
+		t.Fatal("Streamed response should not be buffered.")
+	}
+
+	var lastTransferred, lastTotal int64
+	buffer := &bytes.Buffer{}
+	n, err := CopyTo(buffer, res, func(transferred, total int64) {
+		lastTransferred, lastTotal = transferred, total
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(len(body)) || buffer.String() != body {
+		t.Fatal("Unexpected body length:", n)
+	}
+
+	if lastTransferred != n || lastTotal != n {
+		t.Fatal("Unexpected progress:", lastTransferred, lastTotal)
+	}
+
+	if err = res.Body().Close(); err != nil {
+		t.Fatal("Closing twice should be allowed:", err)
+	}
+}
+
+func TestSaveToFile(t *testing.T) {
+	server := newStreamServer("file content")
+	defer server.Close()
+
+	res, err := Get(server.URL, nil, Stream())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "download")
+	if _, err = SaveToFile(name, res, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "file content" {
+		t.Fatal("Unexpected file content:", string(data))
+	}
+}
+
+func TestBufferedBody(t *testing.T) {
+	server := newStreamServer("buffered")
+	defer server.Close()
+
+	res, err := Get(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(res.Body())
+	if err != nil || string(data) != "buffered" {
+		t.Fatal("Body should read the buffered data:", string(data), err)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	server := newStreamServer("0123456789")
+	defer server.Close()
+
+	if _, err := Get(server.URL, nil, MaxBodySize(10)); err != nil {
+		t.Fatal("Body within the limit should be allowed:", err)
+	}
+
+	if _, err := Get(server.URL, nil, MaxBodySize(9)); err != BodyTooLargeErr {
+		t.Fatal("Body beyond the limit should fail:", err)
+	}
+}
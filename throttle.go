@@ -0,0 +1,359 @@
+package walgo
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reset values above this are taken as unix timestamps rather than a number
+// of seconds.
+const rateLimitEpochThreshold = 1000000000
+
+// RateLimitStatus is the budget a server reports in the rate limit headers
+// of a response.
+type RateLimitStatus struct {
+	// Limit is the number of requests allowed in the window or -1 if it is
+	// unknown.
+	Limit int
+
+	// Remaining is the number of requests left in the window or -1 if it is
+	// unknown.
+	Remaining int
+
+	// Reset is the time the window resets. It is zero if unknown.
+	Reset time.Time
+
+	// Window is the length of the window from the RateLimit-Policy header.
+	// It is zero if unknown.
+	Window time.Duration
+}
+
+// ParseRateLimitHeaders reads the rate limit budget from the headers of a
+// response. It understands the X-RateLimit-Limit/Remaining/Reset headers,
+// the RateLimit-Limit/Remaining/Reset headers of the early IETF drafts and
+// the structured RateLimit and RateLimit-Policy headers of the later ones.
+// When more than one policy is reported the most restrictive is used. Reset
+// values are seconds from now unless they are large enough to be a unix
+// timestamp. It returns false if the headers hold no remaining count.
+func ParseRateLimitHeaders(h http.Header, now time.Time) (s RateLimitStatus, ok bool) {
+	s = RateLimitStatus{Limit: -1, Remaining: -1}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if n, err := strconv.Atoi(strings.TrimSpace(h.Get(prefix + "Limit"))); err == nil {
+			s.Limit = n
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(h.Get(prefix + "Remaining"))); err == nil {
+			s.Remaining = n
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(h.Get(prefix+"Reset")), 10, 64); err == nil {
+			s.Reset = resetTime(n, now)
+		}
+	}
+
+	policies := parseRateLimitPolicy(h.Get("RateLimit-Policy"))
+	if p, ok := policies[""]; ok {
+		if s.Limit < 0 {
+			s.Limit = p.Limit
+		}
+		s.Window = p.Window
+	}
+
+	if value := h.Get("RateLimit"); value != "" {
+		parseRateLimitField(value, now, policies, &s)
+	}
+
+	return s, s.Remaining >= 0
+}
+
+// Converts a reset value to a time.
+func resetTime(n int64, now time.Time) time.Time {
+	if n > rateLimitEpochThreshold {
+		return time.Unix(n, 0)
+	}
+	return now.Add(time.Duration(n) * time.Second)
+}
+
+// Parses the RateLimit header. It is either a list of named items with the
+// remaining count in "r" and the reset in "t", or the older dictionary with
+// "limit", "remaining" and "reset" members.
+func parseRateLimitField(value string, now time.Time, policies map[string]RateLimitStatus, s *RateLimitStatus) {
+	found := false
+	best := RateLimitStatus{Limit: -1, Remaining: -1}
+
+	for _, item := range strings.Split(value, ",") {
+		params := strings.Split(strings.TrimSpace(item), ";")
+
+		if !strings.Contains(params[0], "=") {
+			name := strings.Trim(params[0], `"`)
+			status := RateLimitStatus{Limit: -1, Remaining: -1}
+			if p, ok := policies[name]; ok {
+				status.Limit, status.Window = p.Limit, p.Window
+			}
+
+			for _, param := range params[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				n, err := strconv.ParseInt(v, 10, 64)
+				if err != nil {
+					continue
+				}
+				switch k {
+				case "r":
+					status.Remaining = int(n)
+				case "t":
+					status.Reset = now.Add(time.Duration(n) * time.Second)
+				}
+			}
+
+			if status.Remaining >= 0 && (!found || status.Remaining < best.Remaining) {
+				found = true
+				best = status
+			}
+			continue
+		}
+
+		k, v, _ := strings.Cut(params[0], "=")
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(k) {
+		case "limit":
+			s.Limit = int(n)
+		case "remaining":
+			s.Remaining = int(n)
+		case "reset":
+			s.Reset = resetTime(n, now)
+		}
+	}
+
+	if found {
+		*s = best
+	}
+}
+
+// Parses the RateLimit-Policy header into the quota and window of each
+// named policy. The first policy is also stored under "".
+func parseRateLimitPolicy(value string) (policies map[string]RateLimitStatus) {
+	policies = make(map[string]RateLimitStatus)
+	if value == "" {
+		return policies
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		params := strings.Split(strings.TrimSpace(item), ";")
+		p := RateLimitStatus{Limit: -1, Remaining: -1}
+
+		name := strings.Trim(params[0], `"`)
+		if n, err := strconv.Atoi(name); err == nil {
+			p.Limit = n
+		}
+
+		for _, param := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "q":
+				p.Limit = n
+			case "w":
+				p.Window = time.Duration(n) * time.Second
+			}
+		}
+
+		policies[name] = p
+		if _, ok := policies[""]; !ok {
+			policies[""] = p
+		}
+	}
+
+	return policies
+}
+
+// AdaptiveRateLimitConfig holds the settings for an
+// AdaptiveRateLimitRequester.
+type AdaptiveRateLimitConfig struct {
+	// MaxWait is the longest a request waits for a host to accept requests
+	// again. Requests that would have to wait longer fail with
+	// RateLimitExceededErr. If it is 0 requests wait as long as their
+	// context allows.
+	MaxWait time.Duration
+
+	// Spread spaces out the requests to a host evenly until the reset time
+	// instead of using up the remaining budget right away and then pausing.
+	Spread bool
+}
+
+// AdaptiveRateLimitRequester is a Requester that follows the rate limits
+// servers report in their responses. Requests to a host are paused when
+// its budget is used up or it responds with Retry-After, until the time
+// given by the server.
+type AdaptiveRateLimitRequester struct {
+	requestMethods
+
+	requester Requester
+	config    AdaptiveRateLimitConfig
+	hosts     map[string]*adaptiveHost
+	lock      *sync.Mutex
+}
+
+// The budget last reported by a host.
+type adaptiveHost struct {
+	remaining   int
+	reset       time.Time
+	pausedUntil time.Time
+	next        time.Time
+}
+
+// NewAdaptiveRateLimitRequester creates a new Requester forwarding requests
+// to the given Requester within the limits reported by the servers.
+func NewAdaptiveRateLimitRequester(r Requester, config AdaptiveRateLimitConfig) (ar Requester) {
+	l := &AdaptiveRateLimitRequester{
+		requester: r,
+		config:    config,
+		hosts:     make(map[string]*adaptiveHost),
+		lock:      &sync.Mutex{},
+	}
+	l.send = l.makeRequest
+	return l
+}
+
+func (l *AdaptiveRateLimitRequester) makeRequest(ctx context.Context, urlStr string, p ParameterMap, method string, load *payload, opts ...RequestOption) (r Response, err error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = l.wait(ctx, u.Host); err != nil {
+		return nil, err
+	}
+
+	r, err = l.requester.makeRequest(ctx, urlStr, p, method, load, opts...)
+	if r != nil {
+		l.update(u.Host, r, time.Now())
+	}
+	return r, err
+}
+
+// Waits until the host accepts requests and takes one from its budget.
+func (l *AdaptiveRateLimitRequester) wait(ctx context.Context, host string) (err error) {
+	var deadline time.Time
+	if l.config.MaxWait > 0 {
+		deadline = time.Now().Add(l.config.MaxWait)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		l.lock.Lock()
+		now := time.Now()
+		until := l.reserve(host, now)
+		l.lock.Unlock()
+
+		if until.IsZero() {
+			return nil
+		}
+
+		if !deadline.IsZero() && until.After(deadline) {
+			return RateLimitExceededErr
+		}
+
+		timer := time.NewTimer(until.Sub(now))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Takes a request from the budget of the host and returns the zero time, or
+// returns the time to wait for if the host doesn't accept requests now. Must
+// be called with the lock held.
+func (l *AdaptiveRateLimitRequester) reserve(host string, now time.Time) (until time.Time) {
+	h, ok := l.hosts[host]
+	if !ok {
+		return time.Time{}
+	}
+
+	if !h.reset.IsZero() && !now.Before(h.reset) {
+		h.remaining = -1
+		h.reset = time.Time{}
+	}
+
+	if now.Before(h.pausedUntil) {
+		until = h.pausedUntil
+	}
+	if h.remaining == 0 && h.reset.After(until) {
+		until = h.reset
+	}
+	if l.config.Spread && h.next.After(until) && now.Before(h.next) {
+		until = h.next
+	}
+	if !until.IsZero() {
+		return until
+	}
+
+	if h.remaining > 0 {
+		h.remaining--
+		if l.config.Spread && h.reset.After(now) {
+			h.next = now.Add(h.reset.Sub(now) / time.Duration(h.remaining+1))
+		}
+	}
+
+	if h.remaining < 0 && !now.Before(h.next) {
+		delete(l.hosts, host)
+	}
+
+	return time.Time{}
+}
+
+// Stores the budget the host reported in the response.
+func (l *AdaptiveRateLimitRequester) update(host string, r Response, now time.Time) {
+	status, hasStatus := ParseRateLimitHeaders(r.Header(), now)
+
+	var pause time.Time
+	if r.Code() == http.StatusTooManyRequests || r.Code() == http.StatusServiceUnavailable {
+		if d, ok := retryAfter(r.Header(), now); ok {
+			pause = now.Add(d)
+		} else if hasStatus && status.Remaining == 0 && status.Reset.IsZero() && status.Window > 0 {
+			pause = now.Add(status.Window)
+		}
+	}
+
+	if !hasStatus && pause.IsZero() {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &adaptiveHost{remaining: -1}
+		l.hosts[host] = h
+	}
+
+	if hasStatus {
+		h.remaining = status.Remaining
+		h.reset = status.Reset
+		if h.remaining == 0 && h.reset.IsZero() && status.Window > 0 {
+			h.reset = now.Add(status.Window)
+		}
+	}
+	if pause.After(h.pausedUntil) {
+		h.pausedUntil = pause
+	}
+}
@@ -0,0 +1,119 @@
+package walgo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	for i, c := range []struct {
+		headers  map[string]string
+		expected RateLimitStatus
+		ok       bool
+	}{
+		{
+			map[string]string{"X-RateLimit-Limit": "60", "X-RateLimit-Remaining": "12", "X-RateLimit-Reset": "1700000100"},
+			RateLimitStatus{Limit: 60, Remaining: 12, Reset: now.Add(100 * time.Second)},
+			true,
+		},
+		{
+			map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "0", "RateLimit-Reset": "30"},
+			RateLimitStatus{Limit: 10, Remaining: 0, Reset: now.Add(30 * time.Second)},
+			true,
+		},
+		{
+			map[string]string{"RateLimit": "limit=100, remaining=50, reset=5"},
+			RateLimitStatus{Limit: 100, Remaining: 50, Reset: now.Add(5 * time.Second)},
+			true,
+		},
+		{
+			map[string]string{"RateLimit": `"default";r=5;t=30`, "RateLimit-Policy": `"default";q=100;w=60`},
+			RateLimitStatus{Limit: 100, Remaining: 5, Reset: now.Add(30 * time.Second), Window: time.Minute},
+			true,
+		},
+		{
+			map[string]string{"RateLimit": `"hour";r=500;t=3600, "minute";r=2;t=20`, "RateLimit-Policy": `"hour";q=1000;w=3600, "minute";q=10;w=60`},
+			RateLimitStatus{Limit: 10, Remaining: 2, Reset: now.Add(20 * time.Second), Window: time.Minute},
+			true,
+		},
+		{
+			map[string]string{"Content-Type": "text/plain"},
+			RateLimitStatus{Limit: -1, Remaining: -1},
+			false,
+		},
+	} {
+		h := make(http.Header)
+		for k, v := range c.headers {
+			h.Set(k, v)
+		}
+
+		s, ok := ParseRateLimitHeaders(h, now)
+		if ok != c.ok || s != c.expected {
+			t.Fatalf("Unexpected status for case %d: %+v %v", i, s, ok)
+		}
+	}
+}
+
+func TestAdaptiveRetryAfter(t *testing.T) {
+	count := 0
+	server := newThrottlingServer(&count, map[string]string{"Retry-After": "1"}, http.StatusTooManyRequests)
+	defer server.Close()
+
+	requester := NewAdaptiveRateLimitRequester(defaultRequester, AdaptiveRateLimitConfig{})
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := requester.Get(server.URL, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatal("Second request should wait for Retry-After:", elapsed)
+	}
+}
+
+func TestAdaptiveMaxWait(t *testing.T) {
+	count := 0
+	server := newThrottlingServer(&count, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "3600"}, http.StatusOK)
+	defer server.Close()
+
+	// A request waiting for the max wait before failing would time out the
+	// test.
+	requester := NewAdaptiveRateLimitRequester(defaultRequester, AdaptiveRateLimitConfig{MaxWait: 30 * time.Minute})
+
+	if _, err := requester.Get(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := requester.Get(server.URL, nil)
+	if err != RateLimitExceededErr || count != 1 {
+		t.Fatal("Request should fail fast without being sent:", err, count)
+	}
+}
+
+func TestAdaptiveSpread(t *testing.T) {
+	count := 0
+	server := newThrottlingServer(&count, map[string]string{"RateLimit": "remaining=4, reset=1"}, http.StatusOK)
+	defer server.Close()
+
+	requester := NewAdaptiveRateLimitRequester(defaultRequester, AdaptiveRateLimitConfig{Spread: true})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := requester.Get(server.URL, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatal("Requests should be spread over the window:", elapsed)
+	}
+
+	if count != 3 {
+		t.Fatal("Unexpected number of requests:", count)
+	}
+}